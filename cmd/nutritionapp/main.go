@@ -1,44 +1,244 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"nutritionapp/pkg/client"
 	"nutritionapp/pkg/db"
 	"nutritionapp/pkg/fdc"
 	"nutritionapp/pkg/server"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+const dbPath = "nutritionapp.db"
+
+// Defaults of the FDC response cache, overridden by FDC_CACHE_TTL (a
+// duration such as "72h") and FDC_CACHE_MAX_ENTRIES
+const (
+	defaultCacheTTL        = 30 * 24 * time.Hour
+	defaultCacheMaxEntries = 5000
+)
+
 func main() {
 	if godotenv.Load() != nil {
 		log.Println("No .env file found")
 	}
 
-	// Get API key from environment
-	apiKey := os.Getenv("FDC_API_KEY")
-	if apiKey == "" {
-		log.Fatal("FDC_API_KEY environment variable not set")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "import-fdc":
+			runImportFDC(os.Args[2:])
+			return
+		case "cache":
+			runCache(os.Args[2:])
+			return
+		}
 	}
 
+	serverURL := flag.String("server", "", "URL of a remote NutritionApp server, e.g. http://host:8080")
+	flag.Parse()
+
+	var transport client.Transport
+	if *serverURL != "" {
+		transport = client.NewHTTPTransport(*serverURL)
+	} else {
+		// Create request channel
+		requests := make(chan server.Request)
+
+		// Start server
+		srv := newServer(requests)
+		go srv.Start()
+
+		transport = client.NewChannelTransport(requests)
+	}
+
+	// Start client
+	cli := client.NewClient(transport)
+	fmt.Println("Starting NutritionApp...")
+	cli.Start()
+}
+
+// newServer opens the database and the food sources the server relies on:
+// the custom foods, the local catalog when one was imported, then the FDC API
+// when an API key is set
+func newServer(requests chan server.Request) *server.Server {
 	// Initialize SQLite database
-	sqliteDB, err := db.NewSQLiteDB("nutritionapp.db")
+	sqliteDB, err := db.NewSQLiteDB(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	// Create request channel
-	requests := make(chan server.Request)
+	foodSources := []server.FoodSource{sqliteDB.CustomFoods(), sqliteDB.Recipes()}
+	localSources := len(foodSources)
+	catalog := sqliteDB.Catalog()
+	if size, err := catalog.Size(); err != nil {
+		log.Fatalf("Failed to read the local food catalog: %v", err)
+	} else if size > 0 {
+		foodSources = append(foodSources, catalog)
+	}
 
-	// Start server
-	srv := server.NewServer(sqliteDB, fdc.NewFoodProcessor(apiKey), requests)
-	go srv.Start()
+	if apiKey := os.Getenv("FDC_API_KEY"); apiKey != "" {
+		foodSources = append(foodSources, foodCache(sqliteDB).Wrap(fdc.NewFoodProcessor(apiKey)))
+	}
 
-	// Start client
-	cli := client.NewClient(requests)
-	fmt.Println("Starting NutritionApp...")
-	cli.Start()
+	if len(foodSources) == localSources {
+		log.Println("No FDC food data available, only custom foods and recipes can be searched: set the FDC_API_KEY environment variable or run `nutritionapp import-fdc`")
+	}
+	return server.NewServer(sqliteDB, foodSources, requests)
+}
+
+// foodCache returns the FDC response cache, configured from the environment
+func foodCache(sqliteDB *db.SQLiteDB) *db.FoodCache {
+	ttl := defaultCacheTTL
+	if value := os.Getenv("FDC_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid FDC_CACHE_TTL: %v", err)
+		}
+		ttl = parsed
+	}
+
+	maxEntries := defaultCacheMaxEntries
+	if value := os.Getenv("FDC_CACHE_MAX_ENTRIES"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid FDC_CACHE_MAX_ENTRIES: %v", err)
+		}
+		maxEntries = parsed
+	}
+
+	return sqliteDB.FoodCache(ttl, maxEntries)
+}
+
+// runCache handles `nutritionapp cache stats|clear`
+func runCache(args []string) {
+	if len(args) != 1 || (args[0] != "stats" && args[0] != "clear") {
+		fmt.Println("Usage: nutritionapp cache stats|clear")
+		os.Exit(2)
+	}
+
+	sqliteDB, err := db.NewSQLiteDB(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	cache := foodCache(sqliteDB)
+
+	if args[0] == "clear" {
+		removed, err := cache.Clear()
+		if err != nil {
+			log.Fatalf("Failed to clear the cache: %v", err)
+		}
+		fmt.Printf("Removed %d cached response(s)\n", removed)
+		return
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		log.Fatalf("Failed to read the cache: %v", err)
+	}
+	fmt.Printf("Entries: %d (%d searches, %d food details, %d barcodes)\n", stats.Entries, stats.Searches, stats.Details, stats.Barcodes)
+	fmt.Printf("Expired: %d\n", stats.Expired)
+	fmt.Printf("Size:    %.1f KiB\n", float64(stats.Bytes)/1024)
+	fmt.Printf("Hits:    %d\n", stats.Hits)
+	if stats.Entries > 0 {
+		fmt.Printf("Oldest:  %s\n", stats.Oldest.Local().Format(time.DateTime))
+	}
+}
+
+// runImportFDC handles `nutritionapp import-fdc <csv-dir|zip>`
+func runImportFDC(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: nutritionapp import-fdc <csv-dir|zip>")
+		os.Exit(2)
+	}
+
+	sqliteDB, err := db.NewSQLiteDB(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	var stats fdc.BulkStats
+	err = sqliteDB.ImportCatalog(func(w *db.CatalogWriter) error {
+		stats, err = fdc.ImportBulk(args[0], w)
+		return err
+	})
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	fmt.Printf("Imported %d foods with %d nutrient values and %d portions\n", stats.Foods, stats.Nutrients, stats.Portions)
+}
+
+// runServe handles `nutritionapp serve [-addr :8080]`
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	flags.Parse(args)
+
+	srv := newServer(nil)
+	log.Printf("Serving the NutritionApp API on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv.HTTPHandler()))
+}
+
+// runMigrate handles `nutritionapp migrate [status] [-dry-run]`
+func runMigrate(args []string) {
+	status := len(args) > 0 && args[0] == "status"
+	if status {
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	flags.Parse(args)
+
+	states, err := db.GetMigrationStatus(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	if status {
+		for _, state := range states {
+			label := "pending"
+			if state.Applied {
+				label = "applied"
+			}
+			fmt.Printf("%3d  %s  %-25s  %s\n", state.Version, label, state.AppliedAt, state.Description)
+		}
+		return
+	}
+
+	pending := 0
+	for _, state := range states {
+		if !state.Applied {
+			pending++
+			if *dryRun {
+				fmt.Printf("Would apply migration %d: %s\n", state.Version, state.Description)
+			}
+		}
+	}
+
+	if pending == 0 {
+		fmt.Println("Database is up to date")
+		return
+	}
+	if *dryRun {
+		return
+	}
+
+	if _, err := db.NewSQLiteDB(dbPath); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	fmt.Printf("Applied %d migration(s)\n", pending)
 }
@@ -1,6 +1,6 @@
 module nutritionapp
 
-go 1.21
+go 1.22
 
 require (
 	github.com/joho/godotenv v1.5.1
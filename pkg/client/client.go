@@ -3,22 +3,24 @@ package client
 import (
 	"bufio"
 	"fmt"
-	"nutritionapp/pkg/server"
 	"os"
 	"strings"
+	"time"
 )
 
 // Client handles user interaction through the terminal
 type Client struct {
-	requests chan server.Request
-	reader   *bufio.Reader
+	transport Transport
+	reader    *bufio.Reader
+	userID    int64
+	date      time.Time
 }
 
 // NewClient creates a new client instance
-func NewClient(requests chan server.Request) *Client {
+func NewClient(transport Transport) *Client {
 	return &Client{
-		requests: requests,
-		reader:   bufio.NewReader(os.Stdin),
+		transport: transport,
+		reader:    bufio.NewReader(os.Stdin),
 	}
 }
 
@@ -26,6 +28,7 @@ func NewClient(requests chan server.Request) *Client {
 func (c *Client) Start() {
 	fmt.Println("Welcome to NutritionApp!")
 	fmt.Println("Type 'help' for available commands or 'exit' to quit")
+	c.selectDefaultUser()
 
 	for {
 		fmt.Print("> ")
@@ -46,7 +49,7 @@ func (c *Client) Start() {
 
 		if command == "exit" {
 			fmt.Println("Goodbye!")
-			close(c.requests)
+			c.transport.Close()
 			return
 		}
 
@@ -65,8 +68,18 @@ func (c *Client) handleCommand(command string, args []string) {
 		c.handleMeal(args)
 	case "food":
 		c.handleFood(args)
+	case "add":
+		c.quickAdd(args)
+	case "user":
+		c.handleUser(args)
+	case "date":
+		c.handleDate(args)
+	case "recipe":
+		c.handleRecipe(args)
+	case "weight":
+		c.handleWeight(args)
 	case "report":
-		c.handleReport()
+		c.handleReport(args)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 	}
@@ -77,10 +90,40 @@ func (c *Client) showHelp() {
 	fmt.Println("\nAvailable commands:")
 	fmt.Println("  profile        - Show current profile")
 	fmt.Println("  profile create - Create a new profile")
+	fmt.Println("  user list      - List all profiles")
+	fmt.Println("  user switch ID - Switch to another profile")
 	fmt.Println("  meal add       - Add a new meal")
-	fmt.Println("  meal list      - List today's meals")
-	fmt.Println("  food search    - Search for food items")
-	fmt.Println("  report         - Show daily nutritional report")
+	fmt.Println("  date           - Show the active date")
+	fmt.Println("  date DATE      - Set the active date (YYYY-MM-DD, prev, next, today, yesterday)")
+	fmt.Println("  meal list      - List the active date's meals")
+	fmt.Println("  meal edit      - Rename a meal")
+	fmt.Println("  meal delete    - Delete a meal and its food items")
+	fmt.Println("  meal copy MEAL --from DAY - Copy a meal of another day (YYYY-MM-DD, yesterday, prev...) to the active date")
+	fmt.Println("  meal copy-day FROM TO - Copy every meal of a day to another")
+	fmt.Println("  meal save-template - Save a meal of the active date as a template")
+	fmt.Println("  meal from-template - Add a meal of a saved template to the active date")
+	fmt.Println("  add QUANTITY FOOD, ... [to] MEAL - Add foods in one line, e.g. add 150g chicken breast to lunch")
+	fmt.Println("  food search    - Search for food items (--branded for branded products, --foundation, --legacy, --survey or --all)")
+	fmt.Println("  food barcode CODE - Add a branded product by its UPC/EAN barcode")
+	fmt.Println("  food edit      - Change a food item's quantity or meal")
+	fmt.Println("  food remove    - Remove a food item from a meal")
+	fmt.Println("  food recent    - List the recently logged foods, to add one again")
+	fmt.Println("  food favorites - List the starred and most logged foods")
+	fmt.Println("  food star ID   - Star a food, 'food unstar ID' to unstar it")
+	fmt.Println("  food create    - Create a custom food, for what is not in FDC")
+	fmt.Println("  food list --custom - List the custom foods")
+	fmt.Println("  food edit --custom - Change a custom food")
+	fmt.Println("  recipe create  - Create a recipe from foods, with its servings and cooked weight")
+	fmt.Println("  recipe list    - List the recipes with their nutrition per serving")
+	fmt.Println("  recipe edit    - Change a recipe")
+	fmt.Println("  recipe add     - Add servings of a recipe to a meal")
+	fmt.Println("  weight log     - Record a weigh-in for the active date")
+	fmt.Println("  weight history - Show weigh-ins and the weight trend")
+	fmt.Println("  report         - Show the active date's nutritional report")
+	fmt.Println("  report week    - Report on the week of the active date")
+	fmt.Println("  report month   - Report on the month of the active date")
+	fmt.Println("  report range FROM TO - Report on a range of dates")
+	fmt.Println("  report ... nutrients LIST - Report on micronutrients by FDC number or name (comma separated, or 'all')")
 	fmt.Println("  help           - Show this help message")
 	fmt.Println("  exit           - Exit the application")
 }
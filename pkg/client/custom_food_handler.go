@@ -0,0 +1,256 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"nutritionapp/pkg/server"
+	"strconv"
+	"strings"
+)
+
+// macronutrientPrompts are the nutrients asked for every custom food, by
+// name as accepted by the server
+var macronutrientPrompts = []struct {
+	name  string
+	label string
+}{
+	{"kcal", "Calories (kcal)"},
+	{"protein", "Protein (g)"},
+	{"carbs", "Carbs (g)"},
+	{"fat", "Fat (g)"},
+	{"fiber", "Fiber (g)"},
+}
+
+// createCustomFood asks for the description of a food missing from FDC and
+// stores it
+func (c *Client) createCustomFood() {
+	data, ok := c.promptCustomFood(nil)
+	if !ok {
+		return
+	}
+
+	resp, err := makeRequestTyped[server.CustomFoodResponse](c, server.ReqCreateCustomFood, data)
+	if err != nil {
+		fmt.Printf("Error creating food: %s\n", err)
+		return
+	}
+
+	fmt.Println()
+	displayCustomFood("Created:", resp.Food)
+}
+
+// editCustomFood lets the user pick a custom food and change it
+func (c *Client) editCustomFood() {
+	foods, ok := c.listCustomFoods()
+	if !ok {
+		return
+	}
+
+	fmt.Print("Select food number to edit: ")
+	index := c.readInt() - 1
+	if index < 0 || index >= len(foods) {
+		fmt.Println("Invalid food number")
+		return
+	}
+
+	current := foods[index]
+	data, ok := c.promptCustomFood(&current)
+	if !ok {
+		return
+	}
+	data.ID = current.ID
+
+	resp, err := makeRequestTyped[server.CustomFoodResponse](c, server.ReqUpdateCustomFood, data)
+	if err != nil {
+		fmt.Printf("Error updating food: %s\n", err)
+		return
+	}
+
+	fmt.Println()
+	displayCustomFood("Updated:", resp.Food)
+}
+
+// listCustomFoods shows the custom foods, reporting whether there are any
+func (c *Client) listCustomFoods() ([]server.CustomFoodInfo, bool) {
+	resp, err := makeRequestTyped[server.CustomFoodListResponse](c, server.ReqListCustomFoods, server.ListCustomFoodsData{})
+	if err != nil {
+		fmt.Printf("Error listing custom foods: %s\n", err)
+		return nil, false
+	}
+
+	if len(resp.Foods) == 0 {
+		fmt.Println("No custom foods yet, create one with 'food create'")
+		return nil, false
+	}
+
+	fmt.Println("\nCustom foods:")
+	for i, food := range resp.Foods {
+		displayCustomFood(fmt.Sprintf("%d.", i+1), food)
+	}
+	return resp.Foods, true
+}
+
+// promptCustomFood asks for the fields of a custom food, keeping those of
+// current, when editing one, that are left empty
+func (c *Client) promptCustomFood(current *server.CustomFoodInfo) (server.CustomFoodData, bool) {
+	if current == nil {
+		current = &server.CustomFoodInfo{}
+	}
+	data := server.CustomFoodData{Nutrients: make(map[string]float64)}
+
+	data.Name = c.promptString("Name", current.Name)
+	if data.Name == "" {
+		fmt.Println("Name is required")
+		return data, false
+	}
+	data.BrandOwner = c.promptString("Brand (optional)", current.BrandOwner)
+
+	var ok bool
+	if data.ServingSize, ok = c.promptFloat("Serving size in grams (0 for none)", current.ServingSize); !ok {
+		return data, false
+	}
+	if data.ServingSize > 0 {
+		data.ServingText = c.promptString("Serving description (e.g. 1 cookie, optional)", current.ServingText)
+		data.PerServing = c.confirm("Are the nutrients given per serving rather than per 100g?")
+	}
+
+	// Current amounts are per 100g
+	scale := 1.0
+	if data.PerServing {
+		scale = data.ServingSize / 100
+	}
+	currentMacros := []float64{current.Calories, current.Proteins, current.Carbs, current.Fats, current.Fiber}
+	for i, macro := range macronutrientPrompts {
+		amount, ok := c.promptFloat(macro.label, currentMacros[i]*scale)
+		if !ok {
+			return data, false
+		}
+		data.Nutrients[macro.name] = amount
+	}
+
+	var others []string
+	for _, n := range current.Nutrients {
+		others = append(others, strings.ToLower(n.Name)+"="+formatAmount(n.Amount*scale))
+	}
+	fmt.Print("Other nutrients (e.g. sodium=400, sugars=12")
+	if len(others) > 0 {
+		fmt.Printf(", current %s, empty to keep, 'none' to remove", strings.Join(others, ", "))
+	}
+	fmt.Print("): ")
+	input := c.readString()
+	if input == "" {
+		input = strings.Join(others, ", ")
+	}
+	if input != "none" {
+		amounts, err := parseAssignments(input)
+		if err != nil {
+			fmt.Println(err)
+			return data, false
+		}
+		for _, a := range amounts {
+			data.Nutrients[a.name] = a.value
+		}
+	}
+
+	var portions []string
+	for _, p := range current.Portions {
+		portions = append(portions, p.Name+"="+formatAmount(p.Grams))
+	}
+	fmt.Print("Portions in grams (e.g. 1 slice=30, 1 cup=240")
+	if len(portions) > 0 {
+		fmt.Printf(", current %s, empty to keep, 'none' to remove", strings.Join(portions, ", "))
+	}
+	fmt.Print("): ")
+	input = c.readString()
+	if input == "" {
+		input = strings.Join(portions, ", ")
+	}
+	if input != "none" {
+		weights, err := parseAssignments(input)
+		if err != nil {
+			fmt.Println(err)
+			return data, false
+		}
+		for _, w := range weights {
+			data.Portions = append(data.Portions, server.PortionInfo{Name: w.name, Grams: w.value})
+		}
+	}
+	return data, true
+}
+
+// promptString asks for a text, returning current when the answer is empty
+func (c *Client) promptString(label, current string) string {
+	if current != "" {
+		fmt.Printf("%s (current %s, empty to keep): ", label, current)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	if input := c.readString(); input != "" {
+		return input
+	}
+	return current
+}
+
+// promptFloat asks for a non-negative number, returning current when the
+// answer is empty
+func (c *Client) promptFloat(label string, current float64) (float64, bool) {
+	fmt.Printf("%s [%s]: ", label, formatAmount(current))
+	input := c.readString()
+	if input == "" {
+		return current, true
+	}
+	value, err := strconv.ParseFloat(input, 64)
+	if err != nil || value < 0 {
+		fmt.Println("Invalid number")
+		return 0, false
+	}
+	return value, true
+}
+
+// formatAmount formats an amount with at most two decimals
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(math.Round(amount*100)/100, 'f', -1, 64)
+}
+
+type assignment struct {
+	name  string
+	value float64
+}
+
+// parseAssignments parses a comma separated list such as "sodium=400,
+// sugars=12"
+func parseAssignments(s string) ([]assignment, error) {
+	var assignments []assignment
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q, expected NAME=AMOUNT", strings.TrimSpace(part))
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q", strings.TrimSpace(value))
+		}
+		assignments = append(assignments, assignment{name: strings.TrimSpace(name), value: amount})
+	}
+	return assignments, nil
+}
+
+// displayCustomFood prints a custom food with its other nutrients and
+// portions
+func displayCustomFood(marker string, food server.CustomFoodInfo) {
+	displayFood(marker, food.FoodItem)
+	if len(food.Nutrients) > 0 {
+		var others []string
+		for _, n := range food.Nutrients {
+			others = append(others, fmt.Sprintf("%s %.1f%s", n.Name, n.Amount, n.Unit))
+		}
+		fmt.Printf("   Also: %s\n", strings.Join(others, ", "))
+	}
+	if len(food.Portions) > 0 {
+		fmt.Print("   ")
+		displayPortions(food.Portions)
+	}
+}
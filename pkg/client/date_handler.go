@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"time"
+)
+
+func (c *Client) handleDate(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Active date: %s\n", c.activeDateLabel())
+		return
+	}
+
+	date, ok := c.parseDay(args[0])
+	if !ok {
+		fmt.Println("Usage: date [YYYY-MM-DD|prev|next|today|yesterday]")
+		return
+	}
+	c.date = date
+
+	// Going back to the current day follows the clock again
+	if c.currentDate().Format(server.DateFormat) == time.Now().Format(server.DateFormat) {
+		c.date = time.Time{}
+	}
+
+	fmt.Printf("Active date: %s\n", c.activeDateLabel())
+}
+
+// parseDay parses a day given as YYYY-MM-DD, today, yesterday, or prev and
+// next for the days around the active date
+func (c *Client) parseDay(arg string) (time.Time, bool) {
+	switch arg {
+	case "today":
+		return time.Now(), true
+	case "yesterday":
+		return time.Now().AddDate(0, 0, -1), true
+	case "prev":
+		return c.currentDate().AddDate(0, 0, -1), true
+	case "next":
+		return c.currentDate().AddDate(0, 0, 1), true
+	}
+	date, err := time.ParseInLocation(server.DateFormat, arg, time.Local)
+	return date, err == nil
+}
+
+// currentDate returns the day commands currently apply to
+func (c *Client) currentDate() time.Time {
+	if c.date.IsZero() {
+		return time.Now()
+	}
+	return c.date
+}
+
+// activeDate returns the date to send in requests, empty meaning today
+func (c *Client) activeDate() string {
+	if c.date.IsZero() {
+		return ""
+	}
+	return c.date.Format(server.DateFormat)
+}
+
+func (c *Client) activeDateLabel() string {
+	if c.date.IsZero() {
+		return "today"
+	}
+	return c.date.Format(server.DateFormat)
+}
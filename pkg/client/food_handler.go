@@ -2,92 +2,403 @@ package client
 
 import (
 	"fmt"
+	"nutritionapp/pkg/models"
 	"nutritionapp/pkg/server"
+	"strconv"
+	"strings"
 )
 
 func (c *Client) handleFood(args []string) {
 	if len(args) == 0 {
-		fmt.Println("Usage: food [search]")
+		fmt.Println("Usage: food [search|barcode|edit|remove|create|list --custom|recent|favorites|star ID|unstar ID]")
 		return
 	}
 
-	if args[0] == "search" {
-		fmt.Print("Enter food name to search: ")
-		query := c.readString()
-
-		resp, err := makeRequestTyped[server.SearchFoodResponseData](c, server.ReqSearchFood, server.SearchFoodData{Query: query})
-		if err != nil {
-			fmt.Printf("Error searching for food: %s\n", err)
+	switch args[0] {
+	case "search":
+		c.searchFood(args[1:])
+	case "barcode":
+		c.lookupBarcode(args[1:])
+	case "edit":
+		if len(args) > 1 && args[1] == "--custom" {
+			c.editCustomFood()
+		} else {
+			c.editFood()
+		}
+	case "create":
+		c.createCustomFood()
+	case "list":
+		if len(args) != 2 || args[1] != "--custom" {
+			fmt.Println("Usage: food list --custom")
 			return
 		}
-
-		if len(resp.Foods) == 0 {
-			fmt.Println("No foods found matching your search.")
+		c.listCustomFoods()
+	case "remove":
+		c.removeFood()
+	case "recent":
+		c.listFoodUsage(server.ReqRecentFoods, "Recently logged foods", "No food logged yet")
+	case "favorites":
+		c.listFoodUsage(server.ReqFavoriteFoods, "Favorite foods", "No favorite foods yet, star one with 'food star ID' or log a food twice")
+	case "star", "unstar":
+		if len(args) != 2 {
+			fmt.Printf("Usage: food %s ID\n", args[0])
 			return
 		}
+		c.starFood(args[1], args[0] == "star")
+	default:
+		fmt.Println("Unknown food command. Use 'help' for usage.")
+	}
+}
 
-		c.displayFoodResults(resp.Foods)
+// searchFlags map the `food search` flags to the FDC data types they select
+var searchFlags = map[string][]string{
+	"--foundation": {"Foundation"},
+	"--legacy":     {"SR Legacy"},
+	"--survey":     {"Survey (FNDDS)"},
+	"--branded":    {"Branded"},
+	"--all":        {"Foundation", "SR Legacy", "Survey (FNDDS)", "Branded"},
+}
 
-		// Handle food selection and addition to meal
-		fmt.Print("\nEnter number to add food (or 0 to cancel): ")
-		choice := c.readInt()
-		if choice <= 0 || choice > len(resp.Foods) {
+func (c *Client) searchFood(args []string) {
+	data := server.SearchFoodData{UserID: c.userID}
+	for _, arg := range args {
+		dataTypes, ok := searchFlags[arg]
+		if !ok {
+			fmt.Println("Usage: food search [--foundation] [--legacy] [--survey] [--branded] [--all]")
 			return
 		}
+		data.DataTypes = append(data.DataTypes, dataTypes...)
+	}
+
+	// The foods the user logs are offered before searching
+	picks := c.foodPicks()
+	if len(picks) > 0 {
+		fmt.Println("\nYour foods:")
+		for i, food := range picks {
+			displayFoodUsage(fmt.Sprintf("%d.", i+1), food)
+		}
+		fmt.Print("\nEnter a number to pick one of your foods, or a food name to search: ")
+	} else {
+		fmt.Print("Enter food name to search: ")
+	}
+	data.Query = c.readString()
+
+	if choice, err := strconv.Atoi(data.Query); err == nil && choice > 0 && choice <= len(picks) {
+		c.promptAddFood(picks[choice-1].FoodItem)
+		return
+	}
+
+	selectedFood, ok := c.browseFoods(data)
+	if !ok {
+		return
+	}
+	c.promptAddFood(selectedFood)
+}
+
+// promptAddFood asks for a quantity of a food and the meal to add it to
+func (c *Client) promptAddFood(food server.FoodItem) {
+	displayPortions(food.Portions)
+	fmt.Print("Enter quantity (grams, or e.g. 8 oz, 2 slices, 1/2 cup): ")
+	amount := c.readString()
+	if amount == "" {
+		fmt.Println("Invalid quantity")
+		return
+	}
+
+	c.addFoodToMeal(food, amount)
+}
+
+// addFoodToMeal asks for the meal to add a food to, then adds an amount of
+// it, such as "150g" or "2 slices"
+func (c *Client) addFoodToMeal(food server.FoodItem, amount string) {
+	mealListResp, err := c.fetchMeals()
+	if err != nil {
+		fmt.Printf("Error fetching meal list: %s\n", err)
+		return
+	}
+
+	mealIndex := c.selectMeal(mealListResp.Meals)
+	if mealIndex < 0 {
+		return
+	}
+
+	resp, err := makeRequestTyped[server.FoodQuantityResponse](c, server.ReqAddFood, server.AddFoodData{
+		UserID:    c.userID,
+		Date:      c.activeDate(),
+		MealIndex: mealIndex,
+		FoodID:    food.ID,
+		Amount:    amount,
+	})
+	if err != nil {
+		fmt.Printf("Error adding food to meal: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Added %.0fg of %s to meal\n", resp.Quantity, food.Name)
+}
+
+// lookupBarcode finds a branded product by barcode and adds servings of it
+// to a meal
+func (c *Client) lookupBarcode(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: food barcode CODE")
+		return
+	}
 
-		selectedFood := resp.Foods[choice-1]
+	resp, err := makeRequestTyped[server.LookupBarcodeResponse](c, server.ReqLookupBarcode, server.LookupBarcodeData{UserID: c.userID, Code: args[0]})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	food := resp.Food
+	fmt.Println()
+	displayFood("Found:", food)
 
-		fmt.Print("Enter quantity in grams: ")
-		quantity := c.readFloat()
-		if quantity <= 0 {
-			fmt.Println("Invalid quantity")
+	displayPortions(food.Portions)
+	var amount string
+	if food.ServingSize > 0 {
+		fmt.Printf("\nNumber of servings (1 serving = %.0fg, default 1, or e.g. 50g, 0 to cancel): ", food.ServingSize)
+		amount = c.readString()
+		if amount == "" {
+			amount = "1"
+		}
+		// A bare number counts servings rather than grams
+		if servings, err := strconv.ParseFloat(amount, 64); err == nil {
+			if servings <= 0 {
+				return
+			}
+			amount += " serving"
+		}
+	} else {
+		fmt.Print("\nEnter quantity (grams, or e.g. 8 oz, 0 to cancel): ")
+		amount = c.readString()
+		if amount == "" || amount == "0" {
 			return
 		}
+	}
 
-		// Get meal list to add food
-		mealListResp, err := makeRequestTyped[server.MealListResponse](c, server.ReqListMeals, nil)
+	c.addFoodToMeal(food, amount)
+}
+
+// browseFoods shows the search results page by page, letting the user
+// filter and sort them, until a food is chosen or the search is cancelled
+func (c *Client) browseFoods(data server.SearchFoodData) (server.FoodItem, bool) {
+	data.Page = 1
+	for {
+		resp, err := makeRequestTyped[server.SearchFoodResponseData](c, server.ReqSearchFood, data)
 		if err != nil {
-			fmt.Printf("Error fetching meal list: %s\n", err)
-			return
+			fmt.Printf("Error searching for food: %s\n", err)
+			if server.KindOf(err) != server.ErrInvalid || len(data.Filters) == 0 {
+				return server.FoodItem{}, false
+			}
+			// The server rejected the last filter: drop it and search again
+			data.Filters = data.Filters[:len(data.Filters)-1]
+			continue
 		}
 
-		if len(mealListResp.Meals) == 0 {
-			fmt.Println("No meals available. Add a meal first using 'meal add'")
-			return
+		if resp.TotalHits == 0 && len(data.Filters) == 0 {
+			fmt.Println("No foods found matching your search.")
+			return server.FoodItem{}, false
 		}
 
-		fmt.Println("\nAvailable meals:")
-		for i, meal := range mealListResp.Meals {
-			fmt.Printf("%d. %s (%s)\n", i+1, meal.Name, meal.Time)
+		if len(resp.Foods) > 0 {
+			c.displayFoodResults(resp.Foods)
+		} else {
+			fmt.Println("\nNo food found matches the filters.")
+		}
+		fmt.Printf("\nPage %d of %d (%d results, sorted by %s)\n", resp.Page, resp.TotalPages, resp.TotalHits, sortLabel(data.Sort))
+		if len(data.Filters) > 0 {
+			fmt.Printf("Filters: %s (%d foods hidden)\n", strings.Join(data.Filters, ", "), resp.Filtered)
 		}
 
-		fmt.Print("Select meal number: ")
-		mealIndex := c.readInt() - 1
-		if mealIndex < 0 || mealIndex >= len(mealListResp.Meals) {
-			fmt.Println("Invalid meal number")
-			return
+		fmt.Print("Enter number to add food, n/p for next/previous page, f FILTER to filter (e.g. f protein > 20, f clear), s to change the sort, or 0 to cancel: ")
+		input := c.readString()
+		switch {
+		case input == "n":
+			if resp.Page >= resp.TotalPages {
+				fmt.Println("Already on the last page")
+				continue
+			}
+			data.Page++
+		case input == "p":
+			if data.Page <= 1 {
+				fmt.Println("Already on the first page")
+				continue
+			}
+			data.Page--
+		case input == "s":
+			if sortLabel(data.Sort) == "name" {
+				data.Sort = "relevance"
+			} else {
+				data.Sort = "name"
+			}
+			data.Page = 1
+		case input == "f clear":
+			data.Filters = nil
+			data.Page = 1
+		case strings.HasPrefix(input, "f "):
+			filter := strings.TrimSpace(input[2:])
+			if _, err := models.ParseNutrientFilter(filter); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				continue
+			}
+			data.Filters = append(data.Filters, filter)
+			data.Page = 1
+		default:
+			choice, err := strconv.Atoi(input)
+			if err != nil || choice <= 0 || choice > len(resp.Foods) {
+				return server.FoodItem{}, false
+			}
+			return resp.Foods[choice-1], true
 		}
+	}
+}
+
+func sortLabel(sort string) string {
+	if sort == "" {
+		return "relevance"
+	}
+	return sort
+}
 
-		// Add food to meal
-		_, err = makeRequest(c, server.ReqAddFood, server.AddFoodData{
+func (c *Client) editFood() {
+	meals, mealIndex, foodIndex, ok := c.selectFoodItem()
+	if !ok {
+		return
+	}
+	item := meals[mealIndex].FoodItems[foodIndex]
+
+	displayPortions(item.Portions)
+	fmt.Printf("New quantity (current %.0fg, e.g. 150g or 2 slices, empty to keep): ", item.Quantity)
+	if amount := c.readString(); amount != "" {
+		resp, err := makeRequestTyped[server.FoodQuantityResponse](c, server.ReqUpdateFoodQuantity, server.UpdateFoodQuantityData{
+			UserID:    c.userID,
+			Date:      c.activeDate(),
 			MealIndex: mealIndex,
-			FoodID:    selectedFood.ID,
-			Quantity:  quantity,
+			FoodIndex: foodIndex,
+			Amount:    amount,
 		})
 		if err != nil {
-			fmt.Printf("Error adding food to meal: %s\n", err)
+			fmt.Printf("Error updating food: %s\n", err)
 			return
 		}
+		fmt.Printf("Updated %s to %.0fg\n", item.Name, resp.Quantity)
+	}
+
+	if len(meals) < 2 || !c.confirm("Move it to another meal?") {
+		return
+	}
 
-		fmt.Printf("Added %.0fg of %s to meal\n", quantity, selectedFood.Name)
+	toMealIndex := c.selectMeal(meals)
+	if toMealIndex < 0 || toMealIndex == mealIndex {
+		return
 	}
+
+	err := makeRequest(c, server.ReqMoveFood, server.MoveFoodData{
+		UserID:      c.userID,
+		Date:        c.activeDate(),
+		MealIndex:   mealIndex,
+		FoodIndex:   foodIndex,
+		ToMealIndex: toMealIndex,
+	})
+	if err != nil {
+		fmt.Printf("Error moving food: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Moved %s to %s\n", item.Name, meals[toMealIndex].Name)
+}
+
+func (c *Client) removeFood() {
+	meals, mealIndex, foodIndex, ok := c.selectFoodItem()
+	if !ok {
+		return
+	}
+	item := meals[mealIndex].FoodItems[foodIndex]
+
+	if !c.confirm(fmt.Sprintf("Remove %.0fg of %s?", item.Quantity, item.Name)) {
+		return
+	}
+
+	err := makeRequest(c, server.ReqRemoveFood, server.RemoveFoodData{
+		UserID:    c.userID,
+		Date:      c.activeDate(),
+		MealIndex: mealIndex,
+		FoodIndex: foodIndex,
+	})
+	if err != nil {
+		fmt.Printf("Error removing food: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Removed %s from %s\n", item.Name, meals[mealIndex].Name)
+}
+
+// selectFoodItem lets the user pick a meal and then one of its food items
+func (c *Client) selectFoodItem() (meals []server.MealInfo, mealIndex int, foodIndex int, ok bool) {
+	resp, err := c.fetchMeals()
+	if err != nil {
+		fmt.Printf("Error fetching meal list: %s\n", err)
+		return nil, 0, 0, false
+	}
+
+	mealIndex = c.selectMeal(resp.Meals)
+	if mealIndex < 0 {
+		return nil, 0, 0, false
+	}
+
+	items := resp.Meals[mealIndex].FoodItems
+	if len(items) == 0 {
+		fmt.Println("This meal has no food items")
+		return nil, 0, 0, false
+	}
+
+	fmt.Println("\nFood items:")
+	for i, item := range items {
+		fmt.Printf("%d. %s (%.0fg)\n", i+1, item.Name, item.Quantity)
+	}
+
+	fmt.Print("Select food number: ")
+	foodIndex = c.readInt() - 1
+	if foodIndex < 0 || foodIndex >= len(items) {
+		fmt.Println("Invalid food number")
+		return nil, 0, 0, false
+	}
+
+	return resp.Meals, mealIndex, foodIndex, true
 }
 
 func (c *Client) displayFoodResults(foods []server.FoodItem) {
 	fmt.Println("\nSearch results:")
 	for i, food := range foods {
-		fmt.Printf("%d. %s\n", i+1, food.Name)
-		fmt.Printf("   Per 100g: %.1f kcal, %.1fg protein, %.1fg carbs, %.1fg fat, %.1fg fiber\n",
-			food.Calories, food.Proteins, food.Carbs, food.Fats, food.Fiber)
+		displayFood(fmt.Sprintf("%d.", i+1), food)
+	}
+}
+
+// displayPortions prints the household measures of a food, if any
+func displayPortions(portions []server.PortionInfo) {
+	if len(portions) == 0 {
+		return
+	}
+	var names []string
+	for _, p := range portions {
+		names = append(names, fmt.Sprintf("%s (%.0fg)", p.Name, p.Grams))
+	}
+	fmt.Printf("Portions: %s\n", strings.Join(names, ", "))
+}
+
+// displayFood prints a food with its nutritional values, after a marker
+func displayFood(marker string, food server.FoodItem) {
+	fmt.Printf("%s %s\n", marker, food.Name)
+	switch {
+	case food.BrandOwner != "":
+		fmt.Printf("   %s, by %s\n", food.DataType, food.BrandOwner)
+	case food.DataType != "":
+		fmt.Printf("   %s\n", food.DataType)
+	}
+	if food.ServingSize > 0 {
+		fmt.Printf("   Serving: %.0fg %s\n", food.ServingSize, food.ServingText)
 	}
+	fmt.Printf("   Per 100g: %.1f kcal, %.1fg protein, %.1fg carbs, %.1fg fat, %.1fg fiber\n",
+		food.Calories, food.Proteins, food.Carbs, food.Fats, food.Fiber)
 }
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"strconv"
+	"strings"
+)
+
+// foodPickCount is the number of favorite and of recent foods offered
+// before searching
+const foodPickCount = 5
+
+// listFoodUsage shows the recent or favorite foods, then lets the user add
+// one of them to a meal or star it
+func (c *Client) listFoodUsage(reqType, title, empty string) {
+	for {
+		resp, err := makeRequestTyped[server.FoodUsageResponse](c, reqType, server.FoodUsageData{UserID: c.userID})
+		if err != nil {
+			fmt.Printf("Error listing foods: %s\n", err)
+			return
+		}
+		if len(resp.Foods) == 0 {
+			fmt.Println(empty)
+			return
+		}
+
+		fmt.Printf("\n%s:\n", title)
+		for i, food := range resp.Foods {
+			displayFoodUsage(fmt.Sprintf("%d.", i+1), food)
+		}
+
+		fmt.Print("\nEnter number to add food, * NUMBER to star or unstar it, or 0 to go back: ")
+		input := c.readString()
+		if number, ok := strings.CutPrefix(input, "*"); ok {
+			index, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil || index <= 0 || index > len(resp.Foods) {
+				fmt.Println("Invalid food number")
+				continue
+			}
+			food := resp.Foods[index-1]
+			c.starFood(food.ID, !food.Starred)
+			continue
+		}
+
+		choice, err := strconv.Atoi(input)
+		if err != nil || choice <= 0 || choice > len(resp.Foods) {
+			return
+		}
+		c.promptAddFood(resp.Foods[choice-1].FoodItem)
+		return
+	}
+}
+
+// foodPicks returns the favorite foods followed by the recent ones, without
+// duplicates. Failures only leave the picks out.
+func (c *Client) foodPicks() []server.FoodUsageInfo {
+	var picks []server.FoodUsageInfo
+	seen := make(map[string]bool)
+	for _, reqType := range []string{server.ReqFavoriteFoods, server.ReqRecentFoods} {
+		resp, err := makeRequestTyped[server.FoodUsageResponse](c, reqType, server.FoodUsageData{UserID: c.userID, Limit: foodPickCount})
+		if err != nil {
+			return picks
+		}
+		for _, food := range resp.Foods {
+			if !seen[food.ID] {
+				seen[food.ID] = true
+				picks = append(picks, food)
+			}
+		}
+	}
+	return picks
+}
+
+// starFood marks a food as a favorite, or unmarks it
+func (c *Client) starFood(foodID string, starred bool) {
+	err := makeRequest(c, server.ReqStarFood, server.StarFoodData{UserID: c.userID, FoodID: foodID, Starred: starred})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	if starred {
+		fmt.Printf("Starred %s\n", foodID)
+	} else {
+		fmt.Printf("Unstarred %s\n", foodID)
+	}
+}
+
+// displayFoodUsage prints a food with its ID and how the user logs it
+func displayFoodUsage(marker string, food server.FoodUsageInfo) {
+	star := ""
+	if food.Starred {
+		star = " *"
+	}
+	fmt.Printf("%s %s%s\n", marker, food.Name, star)
+
+	details := []string{food.ID}
+	if food.BrandOwner != "" {
+		details = append(details, "by "+food.BrandOwner)
+	}
+	switch {
+	case food.Uses == 1:
+		details = append(details, "logged once on "+food.LastUsed)
+	case food.Uses > 1:
+		details = append(details, fmt.Sprintf("logged %d times, last on %s", food.Uses, food.LastUsed))
+	}
+	fmt.Printf("   %s, %.0f kcal per 100g\n", strings.Join(details, ", "), food.Calories)
+}
@@ -8,8 +8,7 @@ import (
 
 // Helper functions for reading input
 func (c *Client) readString() string {
-	var input string
-	_, err := fmt.Scanln(&input)
+	input, err := c.reader.ReadString('\n')
 	if err != nil {
 		fmt.Printf("Error reading input: %v\n", err)
 		return ""
@@ -34,3 +33,10 @@ func (c *Client) readFloat() float64 {
 	}
 	return val
 }
+
+// confirm asks a yes/no question, defaulting to no
+func (c *Client) confirm(question string) bool {
+	fmt.Printf("%s (y/N): ", question)
+	answer := strings.ToLower(c.readString())
+	return answer == "y" || answer == "yes"
+}
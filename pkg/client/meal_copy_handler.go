@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"slices"
+	"strings"
+	"time"
+)
+
+// copyMeal copies the meals of a name from another day to the active date
+func (c *Client) copyMeal(args []string) {
+	// The meal name may have several words
+	index := slices.Index(args, "--from")
+	if index < 1 || index != len(args)-2 {
+		fmt.Println("Usage: meal copy MEAL --from DAY, e.g. meal copy breakfast --from yesterday")
+		return
+	}
+	from, ok := c.parseDay(args[index+1])
+	if !ok {
+		fmt.Println("Invalid day, use YYYY-MM-DD, today, yesterday, prev or next")
+		return
+	}
+
+	c.copyMeals(server.CopyMealsData{
+		UserID: c.userID,
+		Date:   c.activeDate(),
+		From:   from.Format(server.DateFormat),
+		Meal:   strings.Join(args[:index], " "),
+	}, c.activeDateLabel())
+}
+
+// copyDay copies every meal of a day to another
+func (c *Client) copyDay(args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: meal copy-day FROM TO, e.g. meal copy-day yesterday today")
+		return
+	}
+	var days []time.Time
+	for _, arg := range args {
+		day, ok := c.parseDay(arg)
+		if !ok {
+			fmt.Printf("Invalid day %q, use YYYY-MM-DD, today, yesterday, prev or next\n", arg)
+			return
+		}
+		days = append(days, day)
+	}
+
+	to := days[1].Format(server.DateFormat)
+	c.copyMeals(server.CopyMealsData{
+		UserID: c.userID,
+		Date:   to,
+		From:   days[0].Format(server.DateFormat),
+	}, to)
+}
+
+func (c *Client) copyMeals(data server.CopyMealsData, target string) {
+	resp, err := makeRequestTyped[server.CopyMealsResponse](c, server.ReqCopyMeals, data)
+	if err != nil {
+		fmt.Printf("Error copying meals: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Copied %d meal(s) with %d food item(s) from %s to %s\n", resp.Meals, resp.Foods, data.From, target)
+}
+
+// saveMealTemplate saves a meal of the active date as a template
+func (c *Client) saveMealTemplate() {
+	meals, err := c.fetchMeals()
+	if err != nil {
+		fmt.Printf("Error fetching meal list: %s\n", err)
+		return
+	}
+
+	mealIndex := c.selectMeal(meals.Meals)
+	if mealIndex < 0 {
+		return
+	}
+	meal := meals.Meals[mealIndex]
+
+	fmt.Printf("Template name (default %s): ", meal.Name)
+	resp, err := makeRequestTyped[server.MealTemplateResponse](c, server.ReqSaveMealTemplate, server.SaveMealTemplateData{
+		UserID:    c.userID,
+		Date:      c.activeDate(),
+		MealIndex: mealIndex,
+		Name:      c.readString(),
+	})
+	if err != nil {
+		fmt.Printf("Error saving template: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Saved template %s with %d food item(s)\n", resp.Template.Name, len(resp.Template.FoodItems))
+}
+
+// addMealFromTemplate lets the user pick a meal template and adds its meal
+// to the active date
+func (c *Client) addMealFromTemplate() {
+	resp, err := makeRequestTyped[server.MealTemplateListResponse](c, server.ReqListMealTemplates, server.ListMealTemplatesData{UserID: c.userID})
+	if err != nil {
+		fmt.Printf("Error listing templates: %s\n", err)
+		return
+	}
+	if len(resp.Templates) == 0 {
+		fmt.Println("No meal templates yet, save a meal with 'meal save-template'")
+		return
+	}
+
+	fmt.Println("\nMeal templates:")
+	for i, template := range resp.Templates {
+		fmt.Printf("%d. %s (%s, %.0f kcal)\n", i+1, template.Name, template.MealName, template.Calories)
+		for _, item := range template.FoodItems {
+			fmt.Printf("   - %s (%.0fg)\n", item.Name, item.Quantity)
+		}
+	}
+
+	fmt.Print("Select template number: ")
+	index := c.readInt() - 1
+	if index < 0 || index >= len(resp.Templates) {
+		fmt.Println("Invalid template number")
+		return
+	}
+
+	meal, err := makeRequestTyped[server.MealInfo](c, server.ReqApplyMealTemplate, server.ApplyMealTemplateData{
+		UserID:     c.userID,
+		Date:       c.activeDate(),
+		TemplateID: resp.Templates[index].ID,
+	})
+	if err != nil {
+		fmt.Printf("Error adding meal: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Added %s with %d food item(s) for %s\n", meal.Name, len(meal.FoodItems), c.activeDateLabel())
+}
@@ -7,7 +7,7 @@ import (
 
 func (c *Client) handleMeal(args []string) {
 	if len(args) == 0 {
-		fmt.Println("Usage: meal [add|list]")
+		fmt.Println("Usage: meal [add|list|edit|delete|copy|copy-day|save-template|from-template]")
 		return
 	}
 
@@ -16,7 +16,7 @@ func (c *Client) handleMeal(args []string) {
 		fmt.Print("Meal name (breakfast/lunch/dinner/snack): ")
 		name := c.readString()
 
-		_, err := makeRequest(c, server.ReqAddMeal, server.AddMealData{Name: name})
+		err := makeRequest(c, server.ReqAddMeal, server.AddMealData{UserID: c.userID, Date: c.activeDate(), Name: name})
 		if err != nil {
 			fmt.Printf("Error adding meal: %s\n", err)
 			return
@@ -25,23 +25,122 @@ func (c *Client) handleMeal(args []string) {
 		fmt.Printf("Added %s meal\n", name)
 
 	case "list":
-		resp, err := makeRequestTyped[server.MealListResponse](c, server.ReqListMeals, nil)
+		resp, err := c.fetchMeals()
 		if err != nil {
 			fmt.Printf("Error fetching meal list: %s\n", err)
 			return
 		}
 
 		c.displayMeals(*resp)
+
+	case "edit":
+		resp, err := c.fetchMeals()
+		if err != nil {
+			fmt.Printf("Error fetching meal list: %s\n", err)
+			return
+		}
+
+		mealIndex := c.selectMeal(resp.Meals)
+		if mealIndex < 0 {
+			return
+		}
+
+		fmt.Print("New meal name: ")
+		name := c.readString()
+		if name == "" {
+			fmt.Println("Invalid meal name")
+			return
+		}
+
+		err = makeRequest(c, server.ReqRenameMeal, server.RenameMealData{
+			UserID:    c.userID,
+			Date:      c.activeDate(),
+			MealIndex: mealIndex,
+			Name:      name,
+		})
+		if err != nil {
+			fmt.Printf("Error renaming meal: %s\n", err)
+			return
+		}
+
+		fmt.Printf("Renamed %s to %s\n", resp.Meals[mealIndex].Name, name)
+
+	case "delete":
+		resp, err := c.fetchMeals()
+		if err != nil {
+			fmt.Printf("Error fetching meal list: %s\n", err)
+			return
+		}
+
+		mealIndex := c.selectMeal(resp.Meals)
+		if mealIndex < 0 {
+			return
+		}
+
+		meal := resp.Meals[mealIndex]
+		if !c.confirm(fmt.Sprintf("Delete %s and its %d food item(s)?", meal.Name, len(meal.FoodItems))) {
+			return
+		}
+
+		err = makeRequest(c, server.ReqDeleteMeal, server.DeleteMealData{
+			UserID:    c.userID,
+			Date:      c.activeDate(),
+			MealIndex: mealIndex,
+		})
+		if err != nil {
+			fmt.Printf("Error deleting meal: %s\n", err)
+			return
+		}
+
+		fmt.Printf("Deleted %s meal\n", meal.Name)
+
+	case "copy":
+		c.copyMeal(args[1:])
+	case "copy-day":
+		c.copyDay(args[1:])
+	case "save-template":
+		c.saveMealTemplate()
+	case "from-template":
+		c.addMealFromTemplate()
+
+	default:
+		fmt.Println("Unknown meal command. Use 'help' for usage.")
+	}
+}
+
+// fetchMeals retrieves the meals of the active date
+func (c *Client) fetchMeals() (*server.MealListResponse, error) {
+	return makeRequestTyped[server.MealListResponse](c, server.ReqListMeals, server.ListMealsData{UserID: c.userID, Date: c.activeDate()})
+}
+
+// selectMeal lets the user pick a meal, returning its index or -1
+func (c *Client) selectMeal(meals []server.MealInfo) int {
+	if len(meals) == 0 {
+		fmt.Println("No meals available. Add a meal first using 'meal add'")
+		return -1
+	}
+
+	fmt.Println("\nAvailable meals:")
+	for i, meal := range meals {
+		fmt.Printf("%d. %s (%s)\n", i+1, meal.Name, meal.Time)
+	}
+
+	fmt.Print("Select meal number: ")
+	mealIndex := c.readInt() - 1
+	if mealIndex < 0 || mealIndex >= len(meals) {
+		fmt.Println("Invalid meal number")
+		return -1
 	}
+	return mealIndex
 }
 
 func (c *Client) displayMeals(response server.MealListResponse) {
 	if len(response.Meals) == 0 {
-		fmt.Println("No meals recorded today.")
+		fmt.Printf("No meals recorded for %s.\n", c.activeDateLabel())
 		return
 	}
 
-	fmt.Println("\n=== Today's Meals ===")
+	fmt.Printf("\n=== Meals for %s ===\n", response.Date)
 	for _, meal := range response.Meals {
 		fmt.Printf("\n%s (at %s)\n", meal.Name, meal.Time)
 		if len(meal.FoodItems) == 0 {
@@ -7,7 +7,7 @@ import (
 
 func (c *Client) handleProfile(args []string) {
 	if len(args) == 0 {
-		resp, err := makeRequestTyped[server.ProfileResponseData](c, server.ReqGetProfile, nil)
+		resp, err := makeRequestTyped[server.ProfileResponseData](c, server.ReqGetProfile, server.GetProfileData{UserID: c.userID})
 		if err != nil {
 			fmt.Printf("Error: %s\n", err)
 			return
@@ -44,26 +44,54 @@ func (c *Client) createProfile() {
 	fmt.Print("Gender (male/female): ")
 	data.Gender = c.readString()
 
-	fmt.Print("Goal (weight loss/muscle gain/maintenance): ")
+	fmt.Print("Goal (weight_loss/maintenance/muscle_gain): ")
 	data.Goal = c.readString()
 
-	_, err := makeRequest(c, server.ReqCreateProfile, data)
+	if data.Goal != "" && data.Goal != "maintenance" {
+		fmt.Print("Target rate in kg/week (0 for default): ")
+		data.GoalRate = c.readFloat()
+	}
+
+	fmt.Print("Protein target in g per kg of body weight (0 for default): ")
+	data.GoalProtein = c.readFloat()
+
+	fmt.Print("Activity level (sedentary/light/moderate/active/very_active): ")
+	data.ActivityLevel = c.readString()
+
+	resp, err := makeRequestTyped[server.ProfileResponseData](c, server.ReqCreateProfile, data)
 	if err != nil {
 		fmt.Printf("Error creating profile: %s\n", err)
 		return
 	}
 
+	c.userID = resp.ID
 	fmt.Println("\nProfile created successfully!")
 }
 
 func (c *Client) displayProfile(profile server.ProfileResponseData) {
 	fmt.Println("\n=== Profile ===")
+	fmt.Printf("ID: %d\n", profile.ID)
 	fmt.Printf("Name: %s %s\n", profile.FirstName, profile.LastName)
 	fmt.Printf("Age: %d\n", profile.Age)
 	fmt.Printf("Weight: %.1f kg\n", profile.Weight)
 	fmt.Printf("Height: %.1f cm\n", profile.Height)
 	fmt.Printf("Gender: %s\n", profile.Gender)
-	fmt.Printf("Goal: %s\n", profile.Goal)
+	fmt.Printf("Goal: %s", profile.Goal)
+	if profile.GoalRate > 0 {
+		fmt.Printf(" (%.2f kg/week)", profile.GoalRate)
+	}
+	fmt.Println()
+	fmt.Printf("Activity Level: %s\n", profile.ActivityLevel)
 	fmt.Printf("BMI: %.1f\n", profile.BMI)
 	fmt.Printf("Estimated Body Fat: %.1f%%\n", profile.BodyFatPerc)
+	fmt.Printf("BMR: %.0f kcal/day (Mifflin-St Jeor)\n", profile.BMR)
+	fmt.Printf("     %.0f kcal/day (Harris-Benedict)\n", profile.BMRHarrisBenedict)
+	fmt.Printf("     %.0f kcal/day (Katch-McArdle)\n", profile.BMRKatchMcArdle)
+	fmt.Printf("TDEE: %.0f kcal/day\n", profile.TDEE)
+	fmt.Println("Daily targets:")
+	fmt.Printf("  Calories: %.0f kcal\n", profile.Targets.Calories)
+	fmt.Printf("  Proteins: %.1f g (%.1f g/kg)\n", profile.Targets.Proteins, profile.GoalProtein)
+	fmt.Printf("  Carbs: %.1f g\n", profile.Targets.Carbs)
+	fmt.Printf("  Fats: %.1f g\n", profile.Targets.Fats)
+	fmt.Printf("  Fiber: %.1f g\n", profile.Targets.Fiber)
 }
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"strconv"
+	"strings"
+)
+
+// quickAdd adds the foods of a line such as "150g chicken breast to lunch" to
+// a meal of the active date, asking which food is meant when several match
+func (c *Client) quickAdd(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: add QUANTITY FOOD[, QUANTITY FOOD...] [to MEAL], e.g. add 2 eggs, 1 slice toast to breakfast")
+		return
+	}
+
+	resp, err := makeRequestTyped[server.FoodLineResponse](c, server.ReqParseFoodLine, server.FoodLineData{
+		UserID: c.userID,
+		Date:   c.activeDate(),
+		Text:   strings.Join(args, " "),
+	})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	var picks []server.FoodMatch
+	for _, entry := range resp.Entries {
+		match, ok := c.pickMatch(entry)
+		if !ok {
+			fmt.Println("Nothing added")
+			return
+		}
+		picks = append(picks, match)
+	}
+
+	mealIndex, mealName, ok := c.quickAddMeal(*resp)
+	if !ok {
+		fmt.Println("Nothing added")
+		return
+	}
+
+	data := server.AddFoodLineData{UserID: c.userID, Date: c.activeDate(), MealIndex: mealIndex, Meal: mealName}
+	for _, pick := range picks {
+		data.Foods = append(data.Foods, server.FoodAmount{FoodID: pick.Food.ID, Quantity: pick.Quantity})
+	}
+	added, err := makeRequestTyped[server.AddFoodLineResponse](c, server.ReqAddFoodLine, data)
+	if err != nil {
+		fmt.Printf("Error adding foods, nothing added: %s\n", err)
+		return
+	}
+	for _, pick := range picks {
+		fmt.Printf("Added %.0fg of %s to %s\n", pick.Quantity, pick.Food.Name, added.Meal)
+	}
+}
+
+// pickMatch returns the food meant by a quick-add entry, asking for it when
+// several foods match
+func (c *Client) pickMatch(entry server.FoodEntryMatches) (server.FoodMatch, bool) {
+	switch len(entry.Matches) {
+	case 0:
+		fmt.Printf("No food found for %q\n", entry.Text)
+		return server.FoodMatch{}, false
+	case 1:
+		return entry.Matches[0], true
+	}
+
+	fmt.Printf("\nSeveral foods match %q:\n", entry.Text)
+	for i, match := range entry.Matches {
+		fmt.Printf("%d. %s", i+1, match.Food.Name)
+		if match.Food.BrandOwner != "" {
+			fmt.Printf(", by %s", match.Food.BrandOwner)
+		}
+		fmt.Printf(" (%.0fg, %.0f kcal)\n", match.Quantity, match.Food.Calories*match.Quantity/100)
+	}
+
+	fmt.Print("Select food number (default 1, 0 to cancel): ")
+	input := c.readString()
+	if input == "" {
+		return entry.Matches[0], true
+	}
+	choice, err := strconv.Atoi(input)
+	if err != nil || choice <= 0 || choice > len(entry.Matches) {
+		return server.FoodMatch{}, false
+	}
+	return entry.Matches[choice-1], true
+}
+
+// quickAddMeal returns the index and name of the meal of a quick-add line,
+// or asks for the meal when the line names none. The index is -1 for a meal
+// the active date does not have yet, which the user agreed to add.
+func (c *Client) quickAddMeal(line server.FoodLineResponse) (int, string, bool) {
+	if line.MealIndex >= 0 {
+		return line.MealIndex, line.Meal, true
+	}
+
+	if line.Meal != "" {
+		if !c.confirm(fmt.Sprintf("There is no %s meal for %s, add it?", line.Meal, c.activeDateLabel())) {
+			return -1, "", false
+		}
+		return -1, line.Meal, true
+	}
+
+	resp, err := c.fetchMeals()
+	if err != nil {
+		fmt.Printf("Error fetching meal list: %s\n", err)
+		return -1, "", false
+	}
+
+	mealIndex := c.selectMeal(resp.Meals)
+	if mealIndex < 0 {
+		return -1, "", false
+	}
+	return mealIndex, resp.Meals[mealIndex].Name, true
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"strconv"
+	"strings"
+)
+
+func (c *Client) handleRecipe(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: recipe [create|list|edit|add]")
+		return
+	}
+
+	switch args[0] {
+	case "create":
+		c.createRecipe()
+	case "list":
+		c.listRecipes()
+	case "edit":
+		c.editRecipe()
+	case "add":
+		c.addRecipeToMeal()
+	default:
+		fmt.Println("Unknown recipe command. Use 'help' for usage.")
+	}
+}
+
+// createRecipe asks for the ingredients of a dish and stores it
+func (c *Client) createRecipe() {
+	data, ok := c.promptRecipe(nil)
+	if !ok {
+		return
+	}
+
+	resp, err := makeRequestTyped[server.RecipeResponse](c, server.ReqCreateRecipe, data)
+	if err != nil {
+		fmt.Printf("Error creating recipe: %s\n", err)
+		return
+	}
+
+	fmt.Println()
+	displayRecipe("Created:", resp.Recipe)
+}
+
+// editRecipe lets the user pick a recipe and change it
+func (c *Client) editRecipe() {
+	recipe, ok := c.selectRecipe("edit")
+	if !ok {
+		return
+	}
+
+	data, ok := c.promptRecipe(&recipe)
+	if !ok {
+		return
+	}
+	data.ID = recipe.ID
+
+	resp, err := makeRequestTyped[server.RecipeResponse](c, server.ReqUpdateRecipe, data)
+	if err != nil {
+		fmt.Printf("Error updating recipe: %s\n", err)
+		return
+	}
+
+	fmt.Println()
+	displayRecipe("Updated:", resp.Recipe)
+}
+
+// addRecipeToMeal adds servings of a recipe to a meal
+func (c *Client) addRecipeToMeal() {
+	recipe, ok := c.selectRecipe("add")
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Number of servings (1 serving = %.0fg, default 1, or e.g. 250g): ", recipe.CookedWeight/recipe.Servings)
+	amount := c.readString()
+	if amount == "" {
+		amount = "1"
+	}
+	// A bare number counts servings rather than grams
+	if servings, err := strconv.ParseFloat(amount, 64); err == nil {
+		if servings <= 0 {
+			return
+		}
+		amount += " serving"
+	}
+
+	c.addFoodToMeal(server.FoodItem{ID: recipe.ID, Name: recipe.Name}, amount)
+}
+
+// listRecipes shows the recipes, reporting whether there are any
+func (c *Client) listRecipes() ([]server.RecipeInfo, bool) {
+	resp, err := makeRequestTyped[server.RecipeListResponse](c, server.ReqListRecipes, server.ListRecipesData{})
+	if err != nil {
+		fmt.Printf("Error listing recipes: %s\n", err)
+		return nil, false
+	}
+
+	if len(resp.Recipes) == 0 {
+		fmt.Println("No recipes yet, create one with 'recipe create'")
+		return nil, false
+	}
+
+	fmt.Println("\nRecipes:")
+	for i, recipe := range resp.Recipes {
+		displayRecipe(fmt.Sprintf("%d.", i+1), recipe)
+	}
+	return resp.Recipes, true
+}
+
+// selectRecipe lists the recipes and asks for one of them
+func (c *Client) selectRecipe(action string) (server.RecipeInfo, bool) {
+	recipes, ok := c.listRecipes()
+	if !ok {
+		return server.RecipeInfo{}, false
+	}
+
+	fmt.Printf("Select recipe number to %s: ", action)
+	index := c.readInt() - 1
+	if index < 0 || index >= len(recipes) {
+		fmt.Println("Invalid recipe number")
+		return server.RecipeInfo{}, false
+	}
+	return recipes[index], true
+}
+
+// promptRecipe asks for the fields and the ingredients of a recipe, keeping
+// those of current, when editing one, that are left empty
+func (c *Client) promptRecipe(current *server.RecipeInfo) (server.RecipeData, bool) {
+	if current == nil {
+		current = &server.RecipeInfo{Servings: 1}
+	}
+	var data server.RecipeData
+
+	data.Name = c.promptString("Name", current.Name)
+	if data.Name == "" {
+		fmt.Println("Name is required")
+		return data, false
+	}
+
+	var ok bool
+	if data.Servings, ok = c.promptFloat("Number of servings", current.Servings); !ok {
+		return data, false
+	}
+	if data.Servings <= 0 {
+		fmt.Println("The number of servings must be positive")
+		return data, false
+	}
+
+	// Names of the ingredients, to list them while editing
+	var names []string
+	for _, item := range current.Ingredients {
+		data.Ingredients = append(data.Ingredients, server.IngredientData{FoodID: item.FoodID, Quantity: item.Quantity})
+		names = append(names, fmt.Sprintf("%.0fg %s", item.Quantity, item.Name))
+	}
+
+	for {
+		if len(names) > 0 {
+			fmt.Println("\nIngredients:")
+			for i, name := range names {
+				fmt.Printf("%d. %s\n", i+1, name)
+			}
+		}
+		fmt.Print("\nEnter a to add an ingredient, r NUMBER to remove one, or nothing when done: ")
+		input := c.readString()
+		switch {
+		case input == "":
+			if len(data.Ingredients) == 0 {
+				fmt.Println("A recipe needs at least one ingredient")
+				return data, false
+			}
+			if data.YieldWeight, ok = c.promptFloat("Cooked weight in grams (0 for the weight of the ingredients)", current.YieldWeight); !ok {
+				return data, false
+			}
+			return data, true
+
+		case input == "a":
+			fmt.Print("Enter ingredient name to search: ")
+			food, ok := c.browseFoods(server.SearchFoodData{UserID: c.userID, Query: c.readString()})
+			if !ok {
+				continue
+			}
+			displayPortions(food.Portions)
+			fmt.Print("Enter quantity (grams, or e.g. 8 oz, 2 slices, 1/2 cup): ")
+			amount := c.readString()
+			if amount == "" {
+				fmt.Println("Invalid quantity")
+				continue
+			}
+			data.Ingredients = append(data.Ingredients, server.IngredientData{FoodID: food.ID, Amount: amount})
+			names = append(names, amount+" "+food.Name)
+
+		case strings.HasPrefix(input, "r "):
+			index, err := strconv.Atoi(strings.TrimSpace(input[2:]))
+			if err != nil || index <= 0 || index > len(names) {
+				fmt.Println("Invalid ingredient number")
+				continue
+			}
+			data.Ingredients = append(data.Ingredients[:index-1], data.Ingredients[index:]...)
+			names = append(names[:index-1], names[index:]...)
+
+		default:
+			fmt.Println("Unknown choice")
+		}
+	}
+}
+
+// displayRecipe prints a recipe with its ingredients and nutritional values
+func displayRecipe(marker string, recipe server.RecipeInfo) {
+	fmt.Printf("%s %s (%s servings, %.0fg cooked", marker, recipe.Name, formatAmount(recipe.Servings), recipe.CookedWeight)
+	if recipe.YieldWeight > 0 {
+		fmt.Printf(" from %.0fg raw", recipe.RawWeight)
+	}
+	fmt.Println(")")
+
+	for _, item := range recipe.Ingredients {
+		fmt.Printf("   - %.0fg %s (%.0f kcal)\n", item.Quantity, item.Name, item.Calories)
+	}
+
+	for _, values := range []struct {
+		label string
+		server.NutritionValues
+	}{{"Per serving", recipe.PerServing}, {"Per 100g", recipe.Per100g}} {
+		fmt.Printf("   %s: %.1f kcal, %.1fg protein, %.1fg carbs, %.1fg fat, %.1fg fiber\n",
+			values.label, values.Calories, values.Proteins, values.Carbs, values.Fats, values.Fiber)
+	}
+}
@@ -3,19 +3,144 @@ package client
 import (
 	"fmt"
 	"nutritionapp/pkg/server"
+	"strings"
+	"time"
 )
 
-func (c *Client) handleReport() {
-	resp, err := makeRequestTyped[server.ReportResponse](c, server.ReqGetReport, nil)
+func (c *Client) handleReport(args []string) {
+	args, nutrients := splitNutrientArgs(args)
+	if len(args) > 0 {
+		c.handlePeriodReport(args, nutrients)
+		return
+	}
+
+	resp, err := makeRequestTyped[server.ReportResponse](c, server.ReqGetReport, server.GetReportData{UserID: c.userID, Date: c.activeDate(), Nutrients: nutrients})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("\n=== Daily Nutritional Report (%s) ===\n", resp.Date)
+	displayTarget("Calories", "%.0f", "kcal", resp.Calories, resp.Targets.Calories, resp.Remaining.Calories)
+	displayTarget("Proteins", "%.1f", "g", resp.Proteins, resp.Targets.Proteins, resp.Remaining.Proteins)
+	displayTarget("Carbs", "%.1f", "g", resp.Carbs, resp.Targets.Carbs, resp.Remaining.Carbs)
+	displayTarget("Fats", "%.1f", "g", resp.Fats, resp.Targets.Fats, resp.Remaining.Fats)
+	displayTarget("Fiber", "%.1f", "g", resp.Fiber, resp.Targets.Fiber, resp.Remaining.Fiber)
+
+	if resp.WeightTrend > 0 {
+		fmt.Printf("Weight trend: %.1f kg (%+.2f kg/week, goal %+.2f kg/week)\n", resp.WeightTrend, resp.WeeklyChange, resp.GoalRate)
+	}
+
+	if len(resp.Nutrients) > 0 {
+		fmt.Println("\nMicronutrients:")
+		for _, n := range resp.Nutrients {
+			fmt.Printf("  %-20s %10.1f %s\n", n.Name, n.Amount, n.Unit)
+		}
+	}
+}
+
+// splitNutrientArgs separates the trailing `nutrients LIST` option from the
+// report arguments. LIST is a comma separated list of FDC nutrient numbers or
+// names, or "all".
+func splitNutrientArgs(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg != "nutrients" {
+			continue
+		}
+
+		var nutrients []string
+		for _, name := range strings.Split(strings.Join(args[i+1:], " "), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				nutrients = append(nutrients, name)
+			}
+		}
+		return args[:i], nutrients
+	}
+	return args, nil
+}
+
+// displayTarget prints a nutrient total against its daily target
+func displayTarget(name, format, unit string, total, target, remaining float64) {
+	if target <= 0 {
+		fmt.Printf("%s: "+format+" %s (no target, the profile measurements are unknown)\n", name, total, unit)
+		return
+	}
+	status := fmt.Sprintf(format+" %s remaining", remaining, unit)
+	if remaining < 0 {
+		status = fmt.Sprintf(format+" %s over", -remaining, unit)
+	}
+	fmt.Printf("%s: "+format+" / "+format+" %s (%s)\n", name, total, target, unit, status)
+}
+
+func (c *Client) handlePeriodReport(args []string, nutrients []string) {
+	day := c.currentDate()
+	data := server.PeriodReportData{UserID: c.userID, Nutrients: nutrients}
+
+	switch args[0] {
+	case "week":
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		from := day.AddDate(0, 0, -offset)
+		data.From = from.Format(server.DateFormat)
+		data.To = from.AddDate(0, 0, 6).Format(server.DateFormat)
+	case "month":
+		from := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.Local)
+		data.From = from.Format(server.DateFormat)
+		data.To = from.AddDate(0, 1, -1).Format(server.DateFormat)
+	case "range":
+		if len(args) < 3 {
+			fmt.Println("Usage: report range FROM TO (YYYY-MM-DD)")
+			return
+		}
+		data.From, data.To = args[1], args[2]
+	default:
+		fmt.Println("Usage: report [week|month|range FROM TO] [nutrients LIST|all]")
+		return
+	}
+
+	resp, err := makeRequestTyped[server.PeriodReportResponse](c, server.ReqGetPeriodReport, data)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	fmt.Println("\n=== Daily Nutritional Report ===")
-	fmt.Printf("Calories: %.0f kcal\n", resp.Calories)
-	fmt.Printf("Proteins: %.1f g\n", resp.Proteins)
-	fmt.Printf("Carbs: %.1f g\n", resp.Carbs)
-	fmt.Printf("Fats: %.1f g\n", resp.Fats)
-	fmt.Printf("Fiber: %.1f g\n", resp.Fiber)
+	c.displayPeriodReport(*resp)
+}
+
+func (c *Client) displayPeriodReport(report server.PeriodReportResponse) {
+	fmt.Printf("\n=== Nutritional Report (%s to %s) ===\n", report.From, report.To)
+	fmt.Printf("Days logged: %d of %d\n", report.DaysLogged, report.Days)
+	if len(report.MissedDays) > 0 {
+		fmt.Printf("Missed days: %s\n", strings.Join(report.MissedDays, ", "))
+	}
+	if report.DaysLogged == 0 {
+		return
+	}
+
+	fmt.Printf("\n%-9s  %10s  %10s  %10s\n", "", "Total", "Avg/day", "Target")
+	fmt.Printf("%-9s  %10.0f  %10.0f  %10.0f  kcal\n", "Calories", report.Totals.Calories, report.Averages.Calories, report.Targets.Calories)
+	fmt.Printf("%-9s  %10.1f  %10.1f  %10.1f  g\n", "Proteins", report.Totals.Proteins, report.Averages.Proteins, report.Targets.Proteins)
+	fmt.Printf("%-9s  %10.1f  %10.1f  %10.1f  g\n", "Carbs", report.Totals.Carbs, report.Averages.Carbs, report.Targets.Carbs)
+	fmt.Printf("%-9s  %10.1f  %10.1f  %10.1f  g\n", "Fats", report.Totals.Fats, report.Averages.Fats, report.Targets.Fats)
+	fmt.Printf("%-9s  %10.1f  %10.1f  %10.1f  g\n", "Fiber", report.Totals.Fiber, report.Averages.Fiber, report.Targets.Fiber)
+
+	fmt.Printf("\nLowest day: %s (%.0f kcal)\n", report.MinDay.Date, report.MinDay.Calories)
+	fmt.Printf("Highest day: %s (%.0f kcal)\n", report.MaxDay.Date, report.MaxDay.Calories)
+
+	fmt.Println("\nDaily breakdown:")
+	for _, day := range report.DailyTotals {
+		if !day.Logged {
+			fmt.Printf("  %s  not logged\n", day.Date)
+			continue
+		}
+		fmt.Printf("  %s  %6.0f kcal  %6.1fg protein  %6.1fg carbs  %6.1fg fat  %6.1fg fiber\n",
+			day.Date, day.Calories, day.Proteins, day.Carbs, day.Fats, day.Fiber)
+	}
+
+	if len(report.Nutrients) > 0 {
+		fmt.Printf("\n%-20s  %10s  %10s\n", "Micronutrients", "Total", "Avg/day")
+		for _, n := range report.Nutrients {
+			fmt.Printf("%-20s  %10.1f  %10.1f  %s\n", n.Name, n.Amount, n.Average, n.Unit)
+		}
+	}
 }
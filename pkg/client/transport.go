@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nutritionapp/pkg/server"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Transport carries requests from the client to a server
+type Transport interface {
+	// Send delivers a request and stores the response data in result, a
+	// pointer to the expected type, or discards it when result is nil
+	Send(reqType string, data any, result any) error
+	Close() error
+}
+
+// ChannelTransport talks to a server running in the same process
+type ChannelTransport struct {
+	requests chan server.Request
+}
+
+// NewChannelTransport creates a transport sending requests on a channel
+func NewChannelTransport(requests chan server.Request) *ChannelTransport {
+	return &ChannelTransport{requests: requests}
+}
+
+func (t *ChannelTransport) Send(reqType string, data any, result any) error {
+	resp := make(chan server.Response)
+	t.requests <- server.Request{
+		Type:   reqType,
+		Data:   data,
+		Return: resp,
+	}
+
+	a := <-resp
+	if a.Error != nil {
+		return a.Error
+	}
+	if result == nil {
+		return nil
+	}
+
+	target := reflect.ValueOf(result).Elem()
+	value := reflect.ValueOf(a.Data)
+	if !value.IsValid() || !value.Type().AssignableTo(target.Type()) {
+		return fmt.Errorf("unexpected response type: %T", a.Data)
+	}
+	target.Set(value)
+	return nil
+}
+
+func (t *ChannelTransport) Close() error {
+	close(t.requests)
+	return nil
+}
+
+// HTTPTransport talks to a remote server started with `nutritionapp serve`
+type HTTPTransport struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPTransport creates a transport sending requests to the server at baseURL
+func NewHTTPTransport(baseURL string) *HTTPTransport {
+	return &HTTPTransport{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (t *HTTPTransport) Send(reqType string, data any, result any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	resp, err := t.client.Post(t.baseURL+"/rpc/"+reqType, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var errResp server.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return server.StatusError(resp.StatusCode, "server returned "+resp.Status)
+		}
+		return server.StatusError(resp.StatusCode, errResp.Error)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return fmt.Errorf("unexpected empty response")
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
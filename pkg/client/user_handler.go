@@ -0,0 +1,78 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"strconv"
+)
+
+func (c *Client) handleUser(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: user [list|switch ID]")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		resp, err := makeRequestTyped[server.UserListResponse](c, server.ReqListUsers, nil)
+		if err != nil {
+			fmt.Printf("Error fetching user list: %s\n", err)
+			return
+		}
+
+		c.displayUsers(*resp)
+
+	case "switch":
+		if len(args) < 2 {
+			fmt.Println("Usage: user switch ID")
+			return
+		}
+
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			fmt.Println("Invalid user ID")
+			return
+		}
+
+		resp, err := makeRequestTyped[server.ProfileResponseData](c, server.ReqGetProfile, server.GetProfileData{UserID: id})
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+
+		c.userID = resp.ID
+		fmt.Printf("Switched to %s %s\n", resp.FirstName, resp.LastName)
+
+	default:
+		fmt.Println("Unknown user command. Use 'help' for usage.")
+	}
+}
+
+// selectDefaultUser makes the first existing profile the active one
+func (c *Client) selectDefaultUser() {
+	resp, err := makeRequestTyped[server.UserListResponse](c, server.ReqListUsers, nil)
+	if err != nil || len(resp.Users) == 0 {
+		fmt.Println("No profile found. Create one using 'profile create'")
+		return
+	}
+
+	user := resp.Users[0]
+	c.userID = user.ID
+	fmt.Printf("Logged in as %s %s\n", user.FirstName, user.LastName)
+}
+
+func (c *Client) displayUsers(response server.UserListResponse) {
+	if len(response.Users) == 0 {
+		fmt.Println("No profiles found.")
+		return
+	}
+
+	fmt.Println("\n=== Profiles ===")
+	for _, user := range response.Users {
+		marker := " "
+		if user.ID == c.userID {
+			marker = "*"
+		}
+		fmt.Printf("%s %d. %s %s\n", marker, user.ID, user.FirstName, user.LastName)
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"nutritionapp/pkg/server"
+	"strconv"
+)
+
+func (c *Client) handleWeight(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: weight [log|history [DAYS]]")
+		return
+	}
+
+	switch args[0] {
+	case "log":
+		c.logWeight()
+
+	case "history":
+		data := server.WeightHistoryData{UserID: c.userID}
+		if len(args) > 1 {
+			days, err := strconv.Atoi(args[1])
+			if err != nil || days <= 0 {
+				fmt.Println("Usage: weight history [DAYS]")
+				return
+			}
+			data.Days = days
+		}
+
+		resp, err := makeRequestTyped[server.WeightHistoryResponse](c, server.ReqWeightHistory, data)
+		if err != nil {
+			fmt.Printf("Error fetching weight history: %s\n", err)
+			return
+		}
+
+		c.displayWeightHistory(*resp)
+
+	default:
+		fmt.Println("Unknown weight command. Use 'help' for usage.")
+	}
+}
+
+func (c *Client) logWeight() {
+	data := server.LogWeightData{UserID: c.userID, Date: c.activeDate()}
+
+	fmt.Print("Weight (kg): ")
+	data.Weight = c.readFloat()
+	if data.Weight <= 0 {
+		fmt.Println("Invalid weight")
+		return
+	}
+
+	fmt.Print("Waist (cm, 0 to skip): ")
+	data.Waist = c.readFloat()
+
+	fmt.Print("Hip (cm, 0 to skip): ")
+	data.Hip = c.readFloat()
+
+	fmt.Print("Neck (cm, 0 to skip): ")
+	data.Neck = c.readFloat()
+
+	fmt.Print("Body fat (%, 0 to skip): ")
+	data.BodyFat = c.readFloat()
+
+	err := makeRequest(c, server.ReqLogWeight, data)
+	if err != nil {
+		fmt.Printf("Error logging weight: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Logged %.1f kg for %s\n", data.Weight, c.activeDateLabel())
+}
+
+func (c *Client) displayWeightHistory(response server.WeightHistoryResponse) {
+	if len(response.Entries) == 0 {
+		fmt.Println("No weigh-ins recorded.")
+		return
+	}
+
+	fmt.Println("\n=== Weight History ===")
+	fmt.Printf("%-10s  %7s  %7s  %6s  %6s  %6s  %6s\n", "Date", "Weight", "Trend", "Waist", "Hip", "Neck", "Fat %")
+	for _, entry := range response.Entries {
+		fmt.Printf("%-10s  %7.1f  %7.1f  %6s  %6s  %6s  %6s\n", entry.Date, entry.Weight, entry.Trend,
+			optional(entry.Waist), optional(entry.Hip), optional(entry.Neck), optional(entry.BodyFat))
+	}
+
+	if len(response.Entries) > 1 {
+		fmt.Printf("\nChange: %+.2f kg/week\n", response.WeeklyChange)
+	}
+}
+
+// optional formats a measurement, showing a dash when it was not taken
+func optional(value float64) string {
+	if value == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", value)
+}
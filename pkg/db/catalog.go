@@ -0,0 +1,260 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Catalog is the local copy of the FDC foods imported with `import-fdc`. It
+// is a food source, its food IDs being FDC IDs.
+type Catalog struct {
+	db *sql.DB
+}
+
+// Catalog returns the local food catalog stored in the database
+func (s *SQLiteDB) Catalog() *Catalog {
+	return &Catalog{db: s.db}
+}
+
+// Name identifies the catalog as a food source. Its foods are FDC foods, with
+// the same IDs as from the FDC API.
+func (c *Catalog) Name() string {
+	return "fdc"
+}
+
+// Size returns the number of foods in the catalog
+func (c *Catalog) Size() (int, error) {
+	var count int
+	err := c.db.QueryRow(`SELECT COUNT(*) FROM catalog_foods`).Scan(&count)
+	return count, err
+}
+
+// catalogFoodColumns are the catalog_foods columns read by scanCatalogFood
+const catalogFoodColumns = `f.fdc_id, f.description, f.data_type, f.brand_owner, f.serving_size, f.serving_text, f.gtin`
+
+func scanCatalogFood(row scanner) (models.Food, error) {
+	var fdcID int64
+	var food models.Food
+	err := row.Scan(&fdcID, &food.Name, &food.DataType, &food.BrandOwner, &food.ServingSize, &food.ServingText, &food.GTIN)
+	food.ID = strconv.FormatInt(fdcID, 10)
+	return food, err
+}
+
+// SearchFoods finds a page of the foods of the query data types whose
+// description contains words starting with every term of the query. By
+// relevance, shortest descriptions come first.
+func (c *Catalog) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	var result models.SearchResult
+	query = query.Normalize()
+
+	var terms []string
+	for _, term := range strings.FieldsFunc(query.Text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		terms = append(terms, term+"*")
+	}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	args := []any{strings.Join(terms, " ")}
+	dataTypes := query.EffectiveDataTypes()
+	for _, dataType := range dataTypes {
+		args = append(args, dataType)
+	}
+	matches := `
+		FROM catalog_foods_fts fts
+		JOIN catalog_foods f ON f.fdc_id = fts.docid
+		WHERE catalog_foods_fts MATCH ?
+			AND f.data_type IN (?` + strings.Repeat(", ?", len(dataTypes)-1) + `)`
+
+	if err := c.db.QueryRow(`SELECT COUNT(*) `+matches, args...).Scan(&result.TotalHits); err != nil {
+		return result, err
+	}
+
+	order := `length(f.description), f.fdc_id`
+	if query.Sort == models.SortName {
+		order = `f.description COLLATE NOCASE, f.fdc_id`
+	}
+	args = append(args, query.PageSize, (query.Page-1)*query.PageSize)
+	rows, err := c.db.Query(`
+		SELECT `+catalogFoodColumns+matches+`
+		ORDER BY `+order+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		food, err := scanCatalogFood(rows)
+		if err != nil {
+			return result, err
+		}
+		result.Foods = append(result.Foods, food)
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	return result, c.loadDetails(result.Foods)
+}
+
+// GetFoodDetails loads a food of the catalog by FDC ID
+func (c *Catalog) GetFoodDetails(id string) (*models.Food, error) {
+	food, err := scanCatalogFood(c.db.QueryRow(`
+		SELECT `+catalogFoodColumns+`
+		FROM catalog_foods f
+		WHERE f.fdc_id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s is not in the local catalog", models.ErrFoodNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	foods := []models.Food{food}
+	if err := c.loadDetails(foods); err != nil {
+		return nil, err
+	}
+	return &foods[0], nil
+}
+
+// LookupBarcode finds the branded product with a barcode, normalized by
+// models.NormalizeGTIN
+func (c *Catalog) LookupBarcode(gtin string) (*models.Food, error) {
+	var fdcID string
+	err := c.db.QueryRow(`SELECT fdc_id FROM catalog_foods WHERE gtin = ? ORDER BY fdc_id DESC`, gtin).Scan(&fdcID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: no product with barcode %s in the local catalog", models.ErrFoodNotFound, gtin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFoodDetails(fdcID)
+}
+
+// catalogDetails are the tables of the nutrients and portions of the catalog
+var catalogDetails = detailTables{nutrients: "catalog_nutrients", portions: "catalog_portions", key: "fdc_id"}
+
+// loadDetails loads the nutrients and portions of foods, with one query for
+// each whatever the number of foods
+func (c *Catalog) loadDetails(foods []models.Food) error {
+	if len(foods) == 0 {
+		return nil
+	}
+	ids := make([]any, len(foods))
+	for i, food := range foods {
+		ids[i] = food.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	nutrients, err := getNutrients(c.db, catalogDetails, placeholders, ids...)
+	if err != nil {
+		return err
+	}
+	portions, err := getPortions(c.db, catalogDetails, placeholders, ids...)
+	if err != nil {
+		return err
+	}
+
+	for i := range foods {
+		food := &foods[i]
+		food.Nutrients = make(map[string]models.Nutrient)
+		for _, n := range nutrients[food.ID] {
+			food.SetNutrient(n)
+		}
+		food.FillEnergy()
+		food.Portions = append([]models.Portion{}, portions[food.ID]...)
+	}
+	return nil
+}
+
+// CatalogWriter adds foods to the catalog during an import
+type CatalogWriter struct {
+	foods     *sql.Stmt
+	brands    *sql.Stmt
+	nutrients *sql.Stmt
+	portions  *sql.Stmt
+}
+
+// AddFood adds a food, without its nutrients and portions
+func (w *CatalogWriter) AddFood(fdcID int64, dataType models.DataType, description string) error {
+	_, err := w.foods.Exec(fdcID, dataType, description)
+	return err
+}
+
+// SetBrand sets the brand owner, label serving and barcode of a branded
+// product
+func (w *CatalogWriter) SetBrand(fdcID int64, brandOwner string, servingSize float64, servingText, gtin string) error {
+	_, err := w.brands.Exec(brandOwner, servingSize, servingText, gtin, fdcID)
+	return err
+}
+
+// AddNutrient adds a nutrient amount, per 100g, to a food
+func (w *CatalogWriter) AddNutrient(fdcID int64, n models.Nutrient) error {
+	_, err := w.nutrients.Exec(fdcID, n.Number, n.Name, n.Unit, n.Amount)
+	return err
+}
+
+// AddPortion adds a household portion, such as "1 cup, chopped", to a food
+func (w *CatalogWriter) AddPortion(fdcID int64, position int, amount float64, description string, gramWeight float64) error {
+	_, err := w.portions.Exec(fdcID, position, amount, description, gramWeight)
+	return err
+}
+
+// ImportCatalog replaces the catalog with the foods added by fill, in a
+// single transaction. The search index is rebuilt once fill returns.
+func (s *SQLiteDB) ImportCatalog(fill func(w *CatalogWriter) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = execAll(tx,
+		`DELETE FROM catalog_foods_fts`,
+		`DELETE FROM catalog_portions`,
+		`DELETE FROM catalog_nutrients`,
+		`DELETE FROM catalog_foods`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var w CatalogWriter
+	statements := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&w.foods, `INSERT INTO catalog_foods (fdc_id, data_type, description) VALUES (?, ?, ?)`},
+		{&w.brands, `UPDATE catalog_foods SET brand_owner = ?, serving_size = ?, serving_text = ?, gtin = ? WHERE fdc_id = ?`},
+		{&w.nutrients, `INSERT OR REPLACE INTO catalog_nutrients (fdc_id, number, name, unit, amount) VALUES (?, ?, ?, ?, ?)`},
+		{&w.portions, `INSERT OR REPLACE INTO catalog_portions (fdc_id, position, amount, description, gram_weight) VALUES (?, ?, ?, ?, ?)`},
+	}
+	for _, statement := range statements {
+		if *statement.stmt, err = tx.Prepare(statement.query); err != nil {
+			return err
+		}
+		defer (*statement.stmt).Close()
+	}
+
+	if err := fill(&w); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO catalog_foods_fts (docid, description)
+		SELECT fdc_id, description FROM catalog_foods
+	`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"errors"
+	"nutritionapp/pkg/models"
+	"testing"
+)
+
+// importTestCatalog imports a few foods into the catalog
+func importTestCatalog(t *testing.T, s *SQLiteDB) *Catalog {
+	t.Helper()
+	err := s.ImportCatalog(func(w *CatalogWriter) error {
+		foods := []struct {
+			fdcID       int64
+			dataType    models.DataType
+			description string
+		}{
+			{1, models.DataTypeSRLegacy, "Milk, whole"},
+			{2, models.DataTypeFoundation, "Milk, reduced fat, 2%"},
+			{3, models.DataTypeSRLegacy, "Bread, whole-wheat"},
+			{4, models.DataTypeBranded, "Chocolate milk"},
+		}
+		for _, food := range foods {
+			if err := w.AddFood(food.fdcID, food.dataType, food.description); err != nil {
+				return err
+			}
+		}
+		if err := w.SetBrand(4, "Dairy Co", 240, "1 cup", "00012345678905"); err != nil {
+			return err
+		}
+		nutrients := []struct {
+			fdcID    int64
+			nutrient models.Nutrient
+		}{
+			{1, models.Nutrient{Number: models.NutrientEnergy, Name: "Energy", Unit: "kcal", Amount: 61}},
+			{1, models.Nutrient{Number: models.NutrientProtein, Name: "Protein", Unit: "g", Amount: 3.2}},
+			// Foundation foods often only have their Atwater energy
+			{2, models.Nutrient{Number: "958", Name: "Energy (Atwater Specific Factors)", Unit: "kcal", Amount: 50}},
+			{3, models.Nutrient{Number: models.NutrientEnergy, Name: "Energy", Unit: "kcal", Amount: 252}},
+		}
+		for _, n := range nutrients {
+			if err := w.AddNutrient(n.fdcID, n.nutrient); err != nil {
+				return err
+			}
+		}
+		if err := w.AddPortion(1, 1, 1, "quart", 976); err != nil {
+			return err
+		}
+		return w.AddPortion(1, 0, 1, "cup", 244)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Catalog()
+}
+
+func TestCatalogSearchFoods(t *testing.T) {
+	s, _ := newTestDB(t)
+	catalog := importTestCatalog(t, s)
+
+	tests := []struct {
+		name      string
+		query     models.FoodQuery
+		wantIDs   []string
+		wantTotal int
+	}{
+		{"shortest first", models.FoodQuery{Text: "milk"}, []string{"1", "2"}, 2},
+		{"word prefixes", models.FoodQuery{Text: "Mil WHO"}, []string{"1"}, 1},
+		{"by name", models.FoodQuery{Text: "milk", Sort: models.SortName}, []string{"2", "1"}, 2},
+		{"data types", models.FoodQuery{Text: "milk", DataTypes: []models.DataType{models.DataTypeBranded}}, []string{"4"}, 1},
+		{"second page", models.FoodQuery{Text: "milk", Page: 2, PageSize: 1}, []string{"2"}, 2},
+		{"no match", models.FoodQuery{Text: "cheese"}, nil, 0},
+		{"no terms", models.FoodQuery{Text: "%!"}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := catalog.SearchFoods(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, food := range result.Foods {
+				ids = append(ids, food.ID)
+			}
+			if len(ids) != len(tt.wantIDs) || result.TotalHits != tt.wantTotal {
+				t.Fatalf("found %v of %d, want %v of %d", ids, result.TotalHits, tt.wantIDs, tt.wantTotal)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("found %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestCatalogSearchFoodsDetails(t *testing.T) {
+	s, _ := newTestDB(t)
+	catalog := importTestCatalog(t, s)
+
+	result, err := catalog.SearchFoods(models.FoodQuery{Text: "milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole, reduced := result.Foods[0], result.Foods[1]
+
+	if whole.Calories != 61 || whole.Proteins != 3.2 || len(whole.Nutrients) != 2 {
+		t.Errorf("whole milk = %v kcal, %v g protein, %d nutrients, want 61 kcal, 3.2 g, 2 nutrients", whole.Calories, whole.Proteins, len(whole.Nutrients))
+	}
+	if len(whole.Portions) != 2 || whole.Portions[0].Description != "cup" || whole.Portions[1].Description != "quart" {
+		t.Errorf("whole milk portions = %v, want cup then quart", whole.Portions)
+	}
+	if reduced.Calories != 50 {
+		t.Errorf("reduced fat milk = %v kcal, want its Atwater energy of 50", reduced.Calories)
+	}
+	if reduced.Portions == nil || len(reduced.Portions) != 0 {
+		t.Errorf("reduced fat milk portions = %#v, want empty", reduced.Portions)
+	}
+}
+
+func TestCatalogGetFoodDetails(t *testing.T) {
+	s, _ := newTestDB(t)
+	catalog := importTestCatalog(t, s)
+
+	food, err := catalog.GetFoodDetails("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Name != "Milk, whole" || food.Calories != 61 || len(food.Portions) != 2 {
+		t.Errorf("food 1 = %q, %v kcal, %d portions, want Milk, whole, 61 kcal, 2 portions", food.Name, food.Calories, len(food.Portions))
+	}
+
+	if _, err := catalog.GetFoodDetails("99"); !errors.Is(err, models.ErrFoodNotFound) {
+		t.Errorf("GetFoodDetails(99) error = %v, want ErrFoodNotFound", err)
+	}
+}
+
+func TestCatalogLookupBarcode(t *testing.T) {
+	s, _ := newTestDB(t)
+	catalog := importTestCatalog(t, s)
+
+	food, err := catalog.LookupBarcode("00012345678905")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.ID != "4" || food.BrandOwner != "Dairy Co" || food.ServingSize != 240 || food.ServingText != "1 cup" {
+		t.Errorf("barcode product = %+v, want food 4 of Dairy Co, 240 per 1 cup", food)
+	}
+
+	if _, err := catalog.LookupBarcode("00000000000017"); !errors.Is(err, models.ErrFoodNotFound) {
+		t.Errorf("unknown barcode error = %v, want ErrFoodNotFound", err)
+	}
+}
+
+func TestImportCatalogReplaces(t *testing.T) {
+	s, _ := newTestDB(t)
+	catalog := importTestCatalog(t, s)
+
+	err := s.ImportCatalog(func(w *CatalogWriter) error {
+		return w.AddFood(10, models.DataTypeSRLegacy, "Cheese, cheddar")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := catalog.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 1 {
+		t.Errorf("catalog size = %d, want 1", size)
+	}
+	result, err := catalog.SearchFoods(models.FoodQuery{Text: "milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalHits != 0 {
+		t.Errorf("found %d foods of the previous import", result.TotalHits)
+	}
+
+	// A failed import keeps the catalog
+	failure := errors.New("truncated file")
+	if err := s.ImportCatalog(func(w *CatalogWriter) error { return failure }); !errors.Is(err, failure) {
+		t.Errorf("ImportCatalog error = %v, want %v", err, failure)
+	}
+	if size, _ := catalog.Size(); size != 1 {
+		t.Errorf("catalog size after a failed import = %d, want 1", size)
+	}
+}
@@ -0,0 +1,251 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CustomFoods are the foods created by users, for what is not in FDC. As FDC
+// foods, they are shared by every user. It is a food source, searched
+// whatever the query data types.
+type CustomFoods struct {
+	db *sql.DB
+}
+
+// CustomFoods returns the custom foods stored in the database
+func (s *SQLiteDB) CustomFoods() *CustomFoods {
+	return &CustomFoods{db: s.db}
+}
+
+// Name identifies the custom foods as a food source
+func (c *CustomFoods) Name() string {
+	return "custom"
+}
+
+// customFoodColumns are the custom_foods columns read by scanCustomFood
+const customFoodColumns = `id, name, brand_owner, serving_size, serving_text`
+
+func scanCustomFood(row scanner) (models.Food, error) {
+	var id int64
+	food := models.Food{DataType: models.DataTypeCustom}
+	err := row.Scan(&id, &food.Name, &food.BrandOwner, &food.ServingSize, &food.ServingText)
+	food.ID = strconv.FormatInt(id, 10)
+	return food, err
+}
+
+// SearchFoods finds a page of the custom foods whose name or brand contains
+// every term of the query. By relevance, shortest names come first.
+func (c *CustomFoods) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	var result models.SearchResult
+	query = query.Normalize()
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	var conditions []string
+	var args []any
+	for _, term := range terms {
+		conditions = append(conditions, `lower(name || ' ' || brand_owner) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	where := ` WHERE ` + strings.Join(conditions, " AND ")
+
+	if err := c.db.QueryRow(`SELECT COUNT(*) FROM custom_foods`+where, args...).Scan(&result.TotalHits); err != nil {
+		return result, err
+	}
+
+	order := `length(name), id`
+	if query.Sort == models.SortName {
+		order = `name COLLATE NOCASE, id`
+	}
+	args = append(args, query.PageSize, (query.Page-1)*query.PageSize)
+	foods, err := c.queryFoods(`
+		SELECT `+customFoodColumns+`
+		FROM custom_foods`+where+`
+		ORDER BY `+order+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	result.Foods = foods
+	return result, err
+}
+
+// ListFoods returns every custom food, by name
+func (c *CustomFoods) ListFoods() ([]models.Food, error) {
+	return c.queryFoods(`
+		SELECT ` + customFoodColumns + `
+		FROM custom_foods
+		ORDER BY name COLLATE NOCASE, id
+	`)
+}
+
+// GetFoodDetails loads a custom food by ID
+func (c *CustomFoods) GetFoodDetails(id string) (*models.Food, error) {
+	food, err := scanCustomFood(c.db.QueryRow(`
+		SELECT `+customFoodColumns+`
+		FROM custom_foods
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: no custom food %s", models.ErrFoodNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	foods := []models.Food{food}
+	if err := c.loadDetails(foods); err != nil {
+		return nil, err
+	}
+	return &foods[0], nil
+}
+
+// CreateFood stores a new custom food, with its nutrients and portions, and
+// sets its ID
+func (c *CustomFoods) CreateFood(food *models.Food) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO custom_foods (name, brand_owner, serving_size, serving_text, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, food.Name, food.BrandOwner, food.ServingSize, food.ServingText, time.Now().Format(time.RFC3339)).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	if err := saveDetails(tx, customFoodDetails, id, food); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	food.ID = strconv.FormatInt(id, 10)
+	food.DataType = models.DataTypeCustom
+	return nil
+}
+
+// UpdateFood replaces a custom food, with its nutrients and portions. The
+// food stored for favorites, meal templates and recipes under the ID the
+// server gives it is updated too, while the meal items already logged keep
+// the data they were logged with.
+func (c *CustomFoods) UpdateFood(food *models.Food) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE custom_foods
+		SET name = ?, brand_owner = ?, serving_size = ?, serving_text = ?
+		WHERE id = ?
+	`, food.Name, food.BrandOwner, food.ServingSize, food.ServingText, food.ID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return fmt.Errorf("%w: no custom food %s", models.ErrFoodNotFound, food.ID)
+	}
+
+	id, err := strconv.ParseInt(food.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := saveDetails(tx, customFoodDetails, id, food); err != nil {
+		return err
+	}
+
+	stored := *food
+	stored.ID = c.Name() + "_" + food.ID
+	stored.DataType = models.DataTypeCustom
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM foods WHERE id = ?`, stored.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		if err := saveFood(tx, &stored); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// queryFoods loads the custom foods selected by a query, with their details
+func (c *CustomFoods) queryFoods(query string, args ...any) ([]models.Food, error) {
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foods []models.Food
+	for rows.Next() {
+		food, err := scanCustomFood(rows)
+		if err != nil {
+			return nil, err
+		}
+		foods = append(foods, food)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.loadDetails(foods); err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+// customFoodDetails are the tables of the nutrients and portions of the
+// custom foods
+var customFoodDetails = detailTables{nutrients: "custom_food_nutrients", portions: "custom_food_portions", key: "food_id"}
+
+// loadDetails loads the nutrients and portions of custom foods, with one
+// query for each whatever the number of foods
+func (c *CustomFoods) loadDetails(foods []models.Food) error {
+	if len(foods) == 0 {
+		return nil
+	}
+	ids := make([]any, len(foods))
+	for i, food := range foods {
+		ids[i] = food.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	nutrients, err := getNutrients(c.db, customFoodDetails, placeholders, ids...)
+	if err != nil {
+		return err
+	}
+	portions, err := getPortions(c.db, customFoodDetails, placeholders, ids...)
+	if err != nil {
+		return err
+	}
+
+	for i := range foods {
+		food := &foods[i]
+		food.Nutrients = make(map[string]models.Nutrient)
+		for _, n := range nutrients[food.ID] {
+			food.SetNutrient(n)
+		}
+		food.Portions = append([]models.Portion{}, portions[food.ID]...)
+	}
+	return nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, with a backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
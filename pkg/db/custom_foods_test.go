@@ -0,0 +1,105 @@
+package db
+
+import (
+	"errors"
+	"nutritionapp/pkg/models"
+	"testing"
+	"time"
+)
+
+// createCustomFood stores a custom food with a nutrient and a portion
+func createCustomFood(t *testing.T, c *CustomFoods, name string, calories float64) *models.Food {
+	t.Helper()
+	food := &models.Food{Name: name, ServingSize: 30, ServingText: "1 slice"}
+	food.SetNutrient(models.Nutrient{Number: models.NutrientEnergy, Name: "Energy", Unit: "kcal", Amount: calories})
+	food.SetNutrient(models.Nutrient{Number: "307", Name: "Sodium, Na", Unit: "mg", Amount: 400})
+	food.Portions = []models.Portion{{Amount: 1, Description: "slice", GramWeight: 30}}
+	if err := c.CreateFood(food); err != nil {
+		t.Fatal(err)
+	}
+	return food
+}
+
+func TestCustomFoodsSearchAndList(t *testing.T) {
+	s, _ := newTestDB(t)
+	c := s.CustomFoods()
+	createCustomFood(t, c, "Sourdough bread", 250)
+	createCustomFood(t, c, "Rye bread", 240)
+	createCustomFood(t, c, "Tomato sauce", 80)
+
+	result, err := c.SearchFoods(models.FoodQuery{Text: "BREAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalHits != 2 || len(result.Foods) != 2 || result.Foods[0].Name != "Rye bread" {
+		t.Fatalf("search = %+v, want Rye bread then Sourdough bread", result)
+	}
+	found := result.Foods[0]
+	if found.Calories != 240 || len(found.Nutrients) != 2 || len(found.Portions) != 1 || found.DataType != models.DataTypeCustom {
+		t.Errorf("found %+v, want 240 kcal, 2 nutrients, 1 portion of a custom food", found)
+	}
+
+	// LIKE wildcards are matched literally
+	if result, _ := c.SearchFoods(models.FoodQuery{Text: "%"}); result.TotalHits != 0 {
+		t.Errorf("searching %% found %d foods", result.TotalHits)
+	}
+
+	foods, err := c.ListFoods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, food := range foods {
+		names = append(names, food.Name)
+	}
+	if len(names) != 3 || names[0] != "Rye bread" || names[2] != "Tomato sauce" {
+		t.Errorf("ListFoods = %v, want the foods by name", names)
+	}
+}
+
+func TestCustomFoodsUpdate(t *testing.T) {
+	s, userID := newTestDB(t)
+	c := s.CustomFoods()
+	food := createCustomFood(t, c, "Sourdough bread", 250)
+
+	// The server logs custom foods under the ID prefixed with the source name
+	logged := *food
+	logged.ID = c.Name() + "_" + food.ID
+	day := time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local)
+	logFood(t, s, userID, day, logged)
+
+	fixed := *food
+	fixed.Nutrients = nil
+	fixed.SetNutrient(models.Nutrient{Number: models.NutrientEnergy, Name: "Energy", Unit: "kcal", Amount: 260})
+	fixed.Portions = nil
+	if err := c.UpdateFood(&fixed); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := c.GetFoodDetails(food.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Calories != 260 || len(updated.Nutrients) != 1 || len(updated.Portions) != 0 {
+		t.Errorf("updated food = %v kcal, %d nutrients, %d portions, want 260 kcal, 1 nutrient, none", updated.Calories, len(updated.Nutrients), len(updated.Portions))
+	}
+
+	stored, err := s.StoredFood(logged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Calories != 260 {
+		t.Errorf("stored food = %v kcal, want 260", stored.Calories)
+	}
+
+	// The log keeps the data the food was logged with
+	if past := loggedFood(t, s, userID, day); past.Calories != 250 || len(past.Portions) != 1 {
+		t.Errorf("logged food = %v kcal, %d portions, want 250 kcal, 1 portion", past.Calories, len(past.Portions))
+	}
+
+	missing := fixed
+	missing.ID = "999"
+	if err := c.UpdateFood(&missing); !errors.Is(err, models.ErrFoodNotFound) {
+		t.Errorf("UpdateFood of an unknown food error = %v, want ErrFoodNotFound", err)
+	}
+}
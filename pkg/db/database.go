@@ -1,21 +1,35 @@
 package db
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"nutritionapp/pkg/models"
+	"strconv"
 	"time"
 )
 
 // UserDatabase defines the interface for database operations
 type UserDatabase interface {
-	GetUser() *models.User
+	GetUser(id int64) *models.User
+	ListUsers() ([]*models.User, error)
 	CreateUser(user *models.User) error
 	UpdateUser(user *models.User) error
-	GetDailyLog(date time.Time) *models.DailyLog
+	GetDailyLog(userID int64, date time.Time) *models.DailyLog
+	GetDailyLogs(userID int64, from, to time.Time) ([]*models.DailyLog, error)
 	SaveDailyLog(log *models.DailyLog) error
 	SaveUser(user *models.User) error
+	SaveWeighIn(userID int64, weighIn *models.WeighIn) error
+	ListWeighIns(userID int64, since time.Time) ([]models.WeighIn, error)
+	GetLatestWeighIn(userID int64) *models.WeighIn
+	RecentFoods(userID int64, limit int) ([]models.FoodUsage, error)
+	FavoriteFoods(userID int64, limit int) ([]models.FoodUsage, error)
+	SetFavorite(userID int64, food *models.Food, starred bool) error
+	StoredFood(id string) (*models.Food, error)
+	SaveMealTemplate(userID int64, template *models.MealTemplate) error
+	ListMealTemplates(userID int64) ([]models.MealTemplate, error)
 }
 
 // SQLiteDB implements UserDatabase using SQLite3
@@ -23,70 +37,89 @@ type SQLiteDB struct {
 	db *sql.DB
 }
 
-// NewSQLiteDB creates a new SQLite database instance
+// NewSQLiteDB creates a new SQLite database instance and applies any
+// pending schema migrations
 func NewSQLiteDB(path string) (*SQLiteDB, error) {
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 
-	if err := createTables(db); err != nil {
+	applied, err := migrate(db)
+	if err != nil {
 		return nil, err
 	}
+	for _, m := range applied {
+		log.Printf("Applied migration %d: %s", m.Version, m.Description)
+	}
 	return &SQLiteDB{db: db}, nil
 }
 
-func createTables(db *sql.DB) error {
-	// Create users table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY,
-			first_name TEXT NOT NULL,
-			last_name TEXT NOT NULL,
-			age INTEGER NOT NULL,
-			weight REAL NOT NULL,
-			height REAL NOT NULL,
-			gender TEXT NOT NULL,
-			goal TEXT NOT NULL
-		)
-	`)
-	if err != nil {
-		return err
-	}
+// userColumns are the users columns read by scanUser, in order
+const userColumns = `id, first_name, last_name, age, weight, height, gender, goal, goal_rate, goal_protein_per_kg, activity_level`
 
-	// Create daily_logs table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS daily_logs (
-			id INTEGER PRIMARY KEY,
-			date TEXT NOT NULL,
-			meals TEXT NOT NULL,
-			UNIQUE(date)
-		)
-	`)
-	return err
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
 }
 
-// GetUser retrieves the user from the database
-func (s *SQLiteDB) GetUser() *models.User {
+func scanUser(row scanner) (*models.User, error) {
 	var user models.User
-	err := s.db.QueryRow(`
-		SELECT first_name, last_name, age, weight, height, gender, goal 
+	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Age, &user.Weight, &user.Height, &user.Gender, &user.Goal.Type, &user.Goal.Rate, &user.Goal.ProteinPerKg, &user.ActivityLevel)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser retrieves a user by ID from the database
+func (s *SQLiteDB) GetUser(id int64) *models.User {
+	user, err := scanUser(s.db.QueryRow(`
+		SELECT `+userColumns+`
 		FROM users 
-		LIMIT 1
-	`).Scan(&user.FirstName, &user.LastName, &user.Age, &user.Weight, &user.Height, &user.Gender, &user.Goal)
+		WHERE id = ?
+	`, id))
 
 	if err != nil {
 		return nil
 	}
-	return &user
+	return user
 }
 
-// CreateUser creates a new user in the database
+// ListUsers retrieves every user from the database, ordered by ID
+func (s *SQLiteDB) ListUsers() ([]*models.User, error) {
+	rows, err := s.db.Query(`
+		SELECT ` + userColumns + `
+		FROM users 
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// CreateUser creates a new user in the database and sets its ID
 func (s *SQLiteDB) CreateUser(user *models.User) error {
-	_, err := s.db.Exec(`
-		INSERT INTO users (first_name, last_name, age, weight, height, gender, goal)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, user.FirstName, user.LastName, user.Age, user.Weight, user.Height, user.Gender, user.Goal)
+	result, err := s.db.Exec(`
+		INSERT INTO users (first_name, last_name, age, weight, height, gender, goal, goal_rate, goal_protein_per_kg, activity_level)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, user.FirstName, user.LastName, user.Age, user.Weight, user.Height, user.Gender, user.Goal.Type, user.Goal.Rate, user.Goal.ProteinPerKg, user.ActivityLevel)
+	if err != nil {
+		return err
+	}
+
+	user.ID, err = result.LastInsertId()
 	return err
 }
 
@@ -94,61 +127,418 @@ func (s *SQLiteDB) CreateUser(user *models.User) error {
 func (s *SQLiteDB) UpdateUser(user *models.User) error {
 	_, err := s.db.Exec(`
 		UPDATE users 
-		SET first_name = ?, last_name = ?, age = ?, weight = ?, height = ?, gender = ?, goal = ?
-		WHERE id = (SELECT id FROM users LIMIT 1)
-	`, user.FirstName, user.LastName, user.Age, user.Weight, user.Height, user.Gender, user.Goal)
+		SET first_name = ?, last_name = ?, age = ?, weight = ?, height = ?, gender = ?, goal = ?, goal_rate = ?, goal_protein_per_kg = ?, activity_level = ?
+		WHERE id = ?
+	`, user.FirstName, user.LastName, user.Age, user.Weight, user.Height, user.Gender, user.Goal.Type, user.Goal.Rate, user.Goal.ProteinPerKg, user.ActivityLevel, user.ID)
 	return err
 }
 
-// GetDailyLog retrieves the daily log for a specific date
-func (s *SQLiteDB) GetDailyLog(date time.Time) *models.DailyLog {
-	dateStr := date.Format("2006-01-02")
-	var mealsJSON string
+// GetDailyLog retrieves a user's daily log for a specific date
+func (s *SQLiteDB) GetDailyLog(userID int64, date time.Time) *models.DailyLog {
+	dailyLog := &models.DailyLog{
+		UserID: userID,
+		Date:   date,
+		Meals:  make([]*models.Meal, 0),
+	}
 
+	var logID int64
 	err := s.db.QueryRow(`
-		SELECT meals FROM daily_logs WHERE date = ?
-	`, dateStr).Scan(&mealsJSON)
+		SELECT id FROM daily_logs WHERE user_id = ? AND date = ?
+	`, userID, date.Format("2006-01-02")).Scan(&logID)
+	if err != nil {
+		return dailyLog
+	}
+
+	meals, err := s.getMeals(logID)
+	if err != nil {
+		log.Printf("Failed to load meals for %s: %v", date.Format("2006-01-02"), err)
+		return dailyLog
+	}
+
+	dailyLog.Meals = meals
+	return dailyLog
+}
 
+// GetDailyLogs retrieves a user's stored daily logs between two dates,
+// inclusive, ordered by date. Days without a log are left out.
+func (s *SQLiteDB) GetDailyLogs(userID int64, from, to time.Time) ([]*models.DailyLog, error) {
+	rows, err := s.db.Query(`
+		SELECT id, date FROM daily_logs
+		WHERE user_id = ? AND date BETWEEN ? AND ?
+		ORDER BY date
+	`, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
 	if err != nil {
-		return &models.DailyLog{
-			Date:  date,
-			Meals: make([]*models.Meal, 0),
+		return nil, err
+	}
+
+	var logIDs []int64
+	var logs []*models.DailyLog
+	for rows.Next() {
+		var logID int64
+		var dateStr string
+		if err := rows.Scan(&logID, &dateStr); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			rows.Close()
+			return nil, err
 		}
+
+		logIDs = append(logIDs, logID)
+		logs = append(logs, &models.DailyLog{UserID: userID, Date: date})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// Parse JSON string into meals array
-	var meals []*models.Meal
-	if err := json.Unmarshal([]byte(mealsJSON), &meals); err != nil {
-		return &models.DailyLog{
-			Date:  date,
-			Meals: make([]*models.Meal, 0),
+	for i, dailyLog := range logs {
+		if dailyLog.Meals, err = s.getMeals(logIDs[i]); err != nil {
+			return nil, err
 		}
 	}
+	return logs, nil
+}
 
-	return &models.DailyLog{
-		Date:  date,
-		Meals: meals,
+// getMeals loads the meals of a daily log, with their food items, in order
+func (s *SQLiteDB) getMeals(logID int64) ([]*models.Meal, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, time FROM meals
+		WHERE daily_log_id = ?
+		ORDER BY position
+	`, logID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	meals := make([]*models.Meal, 0)
+	mealsByID := make(map[int64]*models.Meal)
+	for rows.Next() {
+		var id int64
+		var timeStr string
+		meal := &models.Meal{Foods: make([]models.FoodQuantity, 0)}
+		if err := rows.Scan(&id, &meal.Name, &timeStr); err != nil {
+			return nil, err
+		}
+		meal.Time, _ = time.Parse(time.RFC3339Nano, timeStr)
+		meals = append(meals, meal)
+		mealsByID[id] = meal
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	itemRows, err := s.db.Query(`
+		SELECT mi.meal_id, mi.quantity, v.id, `+versionColumns+`
+		FROM meal_items mi
+		JOIN meals m ON m.id = mi.meal_id
+		JOIN food_versions v ON v.id = mi.food_version_id
+		WHERE m.daily_log_id = ?
+		ORDER BY mi.meal_id, mi.position
+	`, logID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	nutrients, err := getNutrients(s.db, versionDetails, mealVersionIDs, logID)
+	if err != nil {
+		return nil, err
+	}
+	portions, err := getPortions(s.db, versionDetails, mealVersionIDs, logID)
+	if err != nil {
+		return nil, err
+	}
+
+	for itemRows.Next() {
+		var mealID int64
+		var quantity float64
+		var food models.Food
+		if err := itemRows.Scan(append([]any{&mealID, &quantity, &food.Version}, foodFields(&food)...)...); err != nil {
+			return nil, err
+		}
+		version := strconv.FormatInt(food.Version, 10)
+		food.Nutrients = nutrients[version]
+		food.Portions = portions[version]
+		mealsByID[mealID].AddFood(&food, quantity)
+	}
+	return meals, itemRows.Err()
 }
 
-// SaveDailyLog saves a daily log to the database
-func (s *SQLiteDB) SaveDailyLog(log *models.DailyLog) error {
-	dateStr := log.Date.Format("2006-01-02")
+// foodColumns are the foods columns read by foodFields, the table being
+// aliased as f
+const foodColumns = `f.id, f.name, f.calories, f.proteins, f.carbs, f.fats, f.fiber, f.data_type, f.brand_owner, f.serving_size, f.serving_text, f.gtin`
+
+// foodFields returns the scan destinations of foodColumns
+func foodFields(food *models.Food) []any {
+	return []any{&food.ID, &food.Name, &food.Calories, &food.Proteins, &food.Carbs, &food.Fats, &food.Fiber,
+		&food.DataType, &food.BrandOwner, &food.ServingSize, &food.ServingText, &food.GTIN}
+}
+
+// versionColumns are the food_versions columns read by foodFields, the
+// table being aliased as v
+const versionColumns = `v.food_id, v.name, v.calories, v.proteins, v.carbs, v.fats, v.fiber, v.data_type, v.brand_owner, v.serving_size, v.serving_text, v.gtin`
+
+// mealVersionIDs selects the IDs of the food versions eaten in a daily log
+const mealVersionIDs = `
+	SELECT mi.food_version_id
+	FROM meal_items mi
+	JOIN meals m ON m.id = mi.meal_id
+	WHERE m.daily_log_id = ?`
+
+// detailTables name the tables of the nutrients and portions of foods, and
+// their column identifying the food they belong to
+type detailTables struct {
+	nutrients string
+	portions  string
+	key       string
+}
 
-	// Convert meals to JSON
-	mealsJSON, err := json.Marshal(log.Meals)
+var (
+	foodDetails    = detailTables{nutrients: "food_nutrients", portions: "food_portions", key: "food_id"}
+	versionDetails = detailTables{nutrients: "food_version_nutrients", portions: "food_version_portions", key: "version_id"}
+)
+
+// getNutrients loads the nutrients of the foods selected by the ids query,
+// keyed by the key of the tables then nutrient number
+func getNutrients(db *sql.DB, tables detailTables, ids string, args ...any) (map[string]map[string]models.Nutrient, error) {
+	rows, err := db.Query(`
+		SELECT `+tables.key+`, number, name, unit, amount
+		FROM `+tables.nutrients+`
+		WHERE `+tables.key+` IN (`+ids+`)
+	`, args...)
 	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nutrients := make(map[string]map[string]models.Nutrient)
+	for rows.Next() {
+		var key string
+		var n models.Nutrient
+		if err := rows.Scan(&key, &n.Number, &n.Name, &n.Unit, &n.Amount); err != nil {
+			return nil, err
+		}
+		if nutrients[key] == nil {
+			nutrients[key] = make(map[string]models.Nutrient)
+		}
+		nutrients[key][n.Number] = n
+	}
+	return nutrients, rows.Err()
+}
+
+// getPortions loads the portions of the foods selected by the ids query,
+// keyed by the key of the tables, in order
+func getPortions(db *sql.DB, tables detailTables, ids string, args ...any) (map[string][]models.Portion, error) {
+	rows, err := db.Query(`
+		SELECT `+tables.key+`, amount, description, gram_weight
+		FROM `+tables.portions+`
+		WHERE `+tables.key+` IN (`+ids+`)
+		ORDER BY `+tables.key+`, position
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	portions := make(map[string][]models.Portion)
+	for rows.Next() {
+		var key string
+		var p models.Portion
+		if err := rows.Scan(&key, &p.Amount, &p.Description, &p.GramWeight); err != nil {
+			return nil, err
+		}
+		portions[key] = append(portions[key], p)
+	}
+	return portions, rows.Err()
+}
+
+// saveDetails replaces the nutrients and portions of a food
+func saveDetails(tx *sql.Tx, tables detailTables, key any, food *models.Food) error {
+	if _, err := tx.Exec(`DELETE FROM `+tables.nutrients+` WHERE `+tables.key+` = ?`, key); err != nil {
 		return err
 	}
+	if _, err := tx.Exec(`DELETE FROM `+tables.portions+` WHERE `+tables.key+` = ?`, key); err != nil {
+		return err
+	}
+
+	for _, n := range food.Nutrients {
+		_, err := tx.Exec(`
+			INSERT INTO `+tables.nutrients+` (`+tables.key+`, number, name, unit, amount)
+			VALUES (?, ?, ?, ?, ?)
+		`, key, n.Number, n.Name, n.Unit, n.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	for i, p := range food.Portions {
+		_, err := tx.Exec(`
+			INSERT INTO `+tables.portions+` (`+tables.key+`, position, amount, description, gram_weight)
+			VALUES (?, ?, ?, ?, ?)
+		`, key, i, p.Amount, p.Description, p.GramWeight)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveDailyLog saves a daily log to the database, replacing its meals
+func (s *SQLiteDB) SaveDailyLog(log *models.DailyLog) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err = s.db.Exec(`
-		INSERT OR REPLACE INTO daily_logs (date, meals)
+	var logID int64
+	err = tx.QueryRow(`
+		INSERT INTO daily_logs (user_id, date)
 		VALUES (?, ?)
-	`, dateStr, string(mealsJSON))
-	return err
+		ON CONFLICT(user_id, date) DO UPDATE SET date = excluded.date
+		RETURNING id
+	`, log.UserID, log.Date.Format("2006-01-02")).Scan(&logID)
+	if err != nil {
+		return err
+	}
+
+	// Meal items are removed along with their meal
+	if _, err := tx.Exec(`DELETE FROM meals WHERE daily_log_id = ?`, logID); err != nil {
+		return err
+	}
+
+	for i, meal := range log.Meals {
+		result, err := tx.Exec(`
+			INSERT INTO meals (daily_log_id, position, name, time)
+			VALUES (?, ?, ?, ?)
+		`, logID, i, meal.Name, meal.Time.Format(time.RFC3339Nano))
+		if err != nil {
+			return err
+		}
+		mealID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for j, item := range meal.Foods {
+			// Foods loaded from a log keep the version they were logged with
+			if item.Food.Version == 0 {
+				if item.Food.Version, err = saveFoodVersion(tx, item.Food); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Exec(`
+				INSERT INTO meal_items (meal_id, position, food_id, food_version_id, quantity)
+				VALUES (?, ?, ?, ?, ?)
+			`, mealID, j, item.Food.ID, item.Food.Version, item.Quantity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
 }
 
-// SaveUser saves a user to the database (alias for CreateUser)
+// saveFood inserts a food, or replaces its data, nutrients and portions
+// included, if it is already known. Foods are stored with the latest data
+// known, which the logs do not use: they keep the versions they were logged
+// with.
+func saveFood(tx *sql.Tx, food *models.Food) error {
+	_, err := tx.Exec(`
+		INSERT INTO foods (id, name, calories, proteins, carbs, fats, fiber, data_type, brand_owner, serving_size, serving_text, gtin)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			calories = excluded.calories,
+			proteins = excluded.proteins,
+			carbs = excluded.carbs,
+			fats = excluded.fats,
+			fiber = excluded.fiber,
+			data_type = excluded.data_type,
+			brand_owner = excluded.brand_owner,
+			serving_size = excluded.serving_size,
+			serving_text = excluded.serving_text,
+			gtin = excluded.gtin
+	`, food.ID, food.Name, food.Calories, food.Proteins, food.Carbs, food.Fats, food.Fiber,
+		food.DataType, food.BrandOwner, food.ServingSize, food.ServingText, food.GTIN)
+	if err != nil {
+		return err
+	}
+	return saveDetails(tx, foodDetails, food.ID, food)
+}
+
+// ensureFood inserts a food unless it is already known, for the foods of
+// old logs, whose data may be older than the stored one
+func ensureFood(tx *sql.Tx, food *models.Food) error {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM foods WHERE id = ?`, food.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return saveFood(tx, food)
+}
+
+// saveFoodVersion stores the latest data of a food about to be logged, and
+// returns the ID of the version keeping it for the log. Logs of the same
+// data share their version.
+func saveFoodVersion(tx *sql.Tx, food *models.Food) (int64, error) {
+	if err := saveFood(tx, food); err != nil {
+		return 0, err
+	}
+
+	fingerprint, err := foodFingerprint(food)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRow(`SELECT id FROM food_versions WHERE food_id = ? AND fingerprint = ?`, food.ID, fingerprint).Scan(&id)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO food_versions (food_id, fingerprint, name, calories, proteins, carbs, fats, fiber, data_type, brand_owner, serving_size, serving_text, gtin)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, food.ID, fingerprint, food.Name, food.Calories, food.Proteins, food.Carbs, food.Fats, food.Fiber,
+		food.DataType, food.BrandOwner, food.ServingSize, food.ServingText, food.GTIN).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, saveDetails(tx, versionDetails, id, food)
+}
+
+// foodFingerprint hashes the stored data of a food, telling whether two
+// versions hold the same data
+func foodFingerprint(food *models.Food) (string, error) {
+	stored := *food
+	stored.Version = 0
+	if len(stored.Nutrients) == 0 {
+		stored.Nutrients = nil
+	}
+	if len(stored.Portions) == 0 {
+		stored.Portions = nil
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SaveUser creates the user if it has no ID yet, or updates it otherwise
 func (s *SQLiteDB) SaveUser(user *models.User) error {
-	return s.CreateUser(user)
+	if user.ID == 0 {
+		return s.CreateUser(user)
+	}
+	return s.UpdateUser(user)
 }
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"nutritionapp/pkg/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDB creates a migrated database with one user and returns the user ID
+func newTestDB(t *testing.T) (*SQLiteDB, int64) {
+	t.Helper()
+	s, err := NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Age: 30, Weight: 60, Height: 165, Goal: models.Goal{Type: models.GoalMaintenance}}
+	if err := s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return s, user.ID
+}
+
+// logFood saves a daily log of one meal holding a food
+func logFood(t *testing.T, s *SQLiteDB, userID int64, day time.Time, food models.Food) {
+	t.Helper()
+	dailyLog := s.GetDailyLog(userID, day)
+	meal := &models.Meal{Name: "Lunch", Time: day}
+	meal.AddFood(&food, 100)
+	dailyLog.Meals = append(dailyLog.Meals, meal)
+	if err := s.SaveDailyLog(dailyLog); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loggedFood returns the first food of the first meal of a day
+func loggedFood(t *testing.T, s *SQLiteDB, userID int64, day time.Time) *models.Food {
+	t.Helper()
+	dailyLog := s.GetDailyLog(userID, day)
+	if len(dailyLog.Meals) == 0 || len(dailyLog.Meals[0].Foods) == 0 {
+		t.Fatalf("no food logged on %s", day.Format("2006-01-02"))
+	}
+	return dailyLog.Meals[0].Foods[0].Food
+}
+
+func TestSaveDailyLogKeepsLoggedFoodData(t *testing.T) {
+	s, userID := newTestDB(t)
+	monday := time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	bread := models.Food{
+		ID:        "fdc_1",
+		Name:      "Bread",
+		Calories:  250,
+		Nutrients: map[string]models.Nutrient{"307": {Number: "307", Name: "Sodium", Unit: "mg", Amount: 400}},
+		Portions:  []models.Portion{{Amount: 1, Description: "slice", GramWeight: 30}},
+	}
+	logFood(t, s, userID, monday, bread)
+
+	changed := bread
+	changed.Calories = 270
+	changed.Nutrients = map[string]models.Nutrient{}
+	changed.Portions = nil
+	logFood(t, s, userID, tuesday, changed)
+
+	// Changing the Monday log, as when editing a quantity, keeps its data
+	dailyLog := s.GetDailyLog(userID, monday)
+	dailyLog.Meals[0].Foods[0].Quantity = 50
+	if err := s.SaveDailyLog(dailyLog); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := s.StoredFood(bread.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		food          *models.Food
+		wantCalories  float64
+		wantNutrients int
+		wantPortions  int
+	}{
+		{"Monday log", loggedFood(t, s, userID, monday), 250, 1, 1},
+		{"Tuesday log", loggedFood(t, s, userID, tuesday), 270, 0, 0},
+		{"stored food", stored, 270, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if tt.food.Calories != tt.wantCalories || len(tt.food.Nutrients) != tt.wantNutrients || len(tt.food.Portions) != tt.wantPortions {
+			t.Errorf("%s = %v kcal, %d nutrients, %d portions, want %v kcal, %d nutrients, %d portions", tt.name,
+				tt.food.Calories, len(tt.food.Nutrients), len(tt.food.Portions), tt.wantCalories, tt.wantNutrients, tt.wantPortions)
+		}
+	}
+}
+
+func TestSaveDailyLogSharesFoodVersions(t *testing.T) {
+	s, userID := newTestDB(t)
+	day := time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local)
+	egg := models.Food{ID: "fdc_2", Name: "Egg", Calories: 143, Portions: []models.Portion{{Amount: 1, Description: "large", GramWeight: 50}}}
+
+	logFood(t, s, userID, day, egg)
+	logFood(t, s, userID, day.AddDate(0, 0, 1), egg)
+	egg.Calories = 150
+	logFood(t, s, userID, day.AddDate(0, 0, 2), egg)
+
+	var versions int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM food_versions WHERE food_id = ?`, egg.ID).Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != 2 {
+		t.Errorf("%d versions of the food, want 2", versions)
+	}
+}
+
+func TestRecentFoodsOrder(t *testing.T) {
+	s, userID := newTestDB(t)
+	paris := time.FixedZone("Paris", 2*60*60)
+
+	// As text, the Paris time would sort after the later UTC ones
+	logFood(t, s, userID, time.Date(2024, 3, 4, 12, 0, 0, 0, paris), models.Food{ID: "fdc_1", Name: "Bread"})
+	logFood(t, s, userID, time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC), models.Food{ID: "fdc_2", Name: "Egg"})
+	logFood(t, s, userID, time.Date(2024, 3, 4, 11, 0, 0, 5e8, time.UTC), models.Food{ID: "fdc_3", Name: "Milk"})
+
+	recent, err := s.RecentFoods(userID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, usage := range recent {
+		names = append(names, usage.Food.Name)
+	}
+	if len(names) != 3 || names[0] != "Milk" || names[1] != "Egg" || names[2] != "Bread" {
+		t.Errorf("recent foods = %v, want Milk, Egg then Bread", names)
+	}
+	if len(recent) == 3 && !recent[0].LastUsed.Equal(time.Date(2024, 3, 4, 11, 0, 0, 5e8, time.UTC)) {
+		t.Errorf("Milk last used %v, want 11:00:00.5 UTC", recent[0].LastUsed)
+	}
+}
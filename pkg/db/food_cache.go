@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"nutritionapp/pkg/models"
+	"strings"
+	"time"
+)
+
+// cacheTimeFormat orders cache timestamps, kept in UTC, when compared as text
+const cacheTimeFormat = "2006-01-02 15:04:05.000000"
+
+// Kinds of cached responses
+const (
+	cacheSearch  = "search"
+	cacheDetails = "details"
+	cacheBarcode = "barcode"
+)
+
+// foodSource is the food source interface of the server, which the cache
+// wraps
+type foodSource interface {
+	Name() string
+	SearchFoods(query models.FoodQuery) (models.SearchResult, error)
+	GetFoodDetails(id string) (*models.Food, error)
+}
+
+// barcodeSource is implemented by the food sources supporting barcodes
+type barcodeSource interface {
+	LookupBarcode(gtin string) (*models.Food, error)
+}
+
+// contextSource and contextBarcodeSource are implemented by the food sources
+// whose requests can be canceled, as by the server
+type contextSource interface {
+	SearchFoodsContext(ctx context.Context, query models.FoodQuery) (models.SearchResult, error)
+	GetFoodDetailsContext(ctx context.Context, id string) (*models.Food, error)
+}
+
+type contextBarcodeSource interface {
+	LookupBarcodeContext(ctx context.Context, gtin string) (*models.Food, error)
+}
+
+// FoodCache stores food source responses, so that repeated lookups do not
+// reach the network. Entries expire after the TTL, and the least recently
+// used ones are evicted past the maximum number of entries, if positive.
+type FoodCache struct {
+	db         *sql.DB
+	ttl        time.Duration
+	maxEntries int
+}
+
+// CacheStats describes the content of the food cache
+type CacheStats struct {
+	Entries  int
+	Searches int
+	Details  int
+	Barcodes int
+	Expired  int
+	Bytes    int64
+	Hits     int64
+	Oldest   time.Time
+}
+
+// FoodCache returns the food cache stored in the database
+func (s *SQLiteDB) FoodCache(ttl time.Duration, maxEntries int) *FoodCache {
+	return &FoodCache{db: s.db, ttl: ttl, maxEntries: maxEntries}
+}
+
+// Wrap returns a food source answering from the cache when it can, and
+// from source otherwise
+func (c *FoodCache) Wrap(source foodSource) *CachedSource {
+	return &CachedSource{cache: c, source: source}
+}
+
+// Stats summarizes the cache content
+func (c *FoodCache) Stats() (CacheStats, error) {
+	var stats CacheStats
+	var oldest sql.NullString
+	err := c.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE kind = ?),
+			COUNT(*) FILTER (WHERE created_at < ?),
+			COALESCE(SUM(length(data)), 0),
+			COALESCE(SUM(hits), 0),
+			MIN(created_at)
+		FROM food_cache
+	`, cacheSearch, cacheDetails, cacheBarcode, c.expiry()).Scan(
+		&stats.Entries, &stats.Searches, &stats.Details, &stats.Barcodes, &stats.Expired, &stats.Bytes, &stats.Hits, &oldest)
+	if err != nil {
+		return stats, err
+	}
+	if oldest.Valid {
+		stats.Oldest, _ = time.Parse(cacheTimeFormat, oldest.String)
+	}
+	return stats, nil
+}
+
+// Clear removes every cached response and returns how many there were
+func (c *FoodCache) Clear() (int64, error) {
+	result, err := c.db.Exec(`DELETE FROM food_cache`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// expiry returns the creation time before which entries are expired
+func (c *FoodCache) expiry() string {
+	return time.Now().UTC().Add(-c.ttl).Format(cacheTimeFormat)
+}
+
+// get loads a cached response into value, reporting whether it was found
+func (c *FoodCache) get(source, kind, key string, value any) bool {
+	var data []byte
+	err := c.db.QueryRow(`
+		UPDATE food_cache SET used_at = ?, hits = hits + 1
+		WHERE source = ? AND kind = ? AND key = ? AND created_at >= ?
+		RETURNING data
+	`, time.Now().UTC().Format(cacheTimeFormat), source, kind, key, c.expiry()).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err == nil {
+		err = json.Unmarshal(data, value)
+	}
+	if err != nil {
+		log.Printf("Failed to read the food cache: %v", err)
+		return false
+	}
+	return true
+}
+
+// put evicts expired entries, stores responses then evicts the least
+// recently used entries. Existing entries are only replaced when overwrite
+// is set.
+func (c *FoodCache) put(source, kind string, values map[string]any, overwrite bool) {
+	if err := c.store(source, kind, values, overwrite); err != nil {
+		log.Printf("Failed to update the food cache: %v", err)
+	}
+}
+
+func (c *FoodCache) store(source, kind string, values map[string]any, overwrite bool) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM food_cache WHERE created_at < ?`, c.expiry()); err != nil {
+		return err
+	}
+
+	conflict := `DO NOTHING`
+	if overwrite {
+		conflict = `DO UPDATE SET data = excluded.data, created_at = excluded.created_at, used_at = excluded.used_at, hits = 0`
+	}
+	now := time.Now().UTC().Format(cacheTimeFormat)
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO food_cache (source, kind, key, data, created_at, used_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source, kind, key) `+conflict,
+			source, kind, key, data, now, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.maxEntries > 0 {
+		_, err := tx.Exec(`
+			DELETE FROM food_cache WHERE rowid IN (
+				SELECT rowid FROM food_cache ORDER BY used_at DESC LIMIT -1 OFFSET ?
+			)
+		`, c.maxEntries)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CachedSource is a food source backed by the food cache
+type CachedSource struct {
+	cache  *FoodCache
+	source foodSource
+}
+
+func (s *CachedSource) Name() string {
+	return s.source.Name()
+}
+
+func (s *CachedSource) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	return s.SearchFoodsContext(context.Background(), query)
+}
+
+// SearchFoodsContext returns the cached results of the query. Fresh results
+// are also cached as details, so that adding a food just found is instant,
+// unless the search left out their portions.
+func (s *CachedSource) SearchFoodsContext(ctx context.Context, query models.FoodQuery) (models.SearchResult, error) {
+	query = query.Normalize()
+	key := fmt.Sprintf("%s|%d|%d|%s", strings.Join(strings.Fields(strings.ToLower(query.Text)), " "), query.Page, query.PageSize, query.Sort)
+	for _, dataType := range query.EffectiveDataTypes() {
+		key += "|" + string(dataType)
+	}
+
+	var result models.SearchResult
+	if s.cache.get(s.Name(), cacheSearch, key, &result) {
+		return result, nil
+	}
+
+	var err error
+	if cancelable, ok := s.source.(contextSource); ok {
+		result, err = cancelable.SearchFoodsContext(ctx, query)
+	} else {
+		result, err = s.source.SearchFoods(query)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	s.cache.put(s.Name(), cacheSearch, map[string]any{key: result}, true)
+	details := make(map[string]any)
+	for _, food := range result.Foods {
+		if len(food.Portions) > 0 {
+			details[food.ID] = food
+		}
+	}
+	s.cache.put(s.Name(), cacheDetails, details, false)
+	return result, nil
+}
+
+func (s *CachedSource) GetFoodDetails(id string) (*models.Food, error) {
+	return s.GetFoodDetailsContext(context.Background(), id)
+}
+
+// GetFoodDetailsContext returns the cached details of a food
+func (s *CachedSource) GetFoodDetailsContext(ctx context.Context, id string) (*models.Food, error) {
+	var food models.Food
+	if s.cache.get(s.Name(), cacheDetails, id, &food) {
+		return &food, nil
+	}
+
+	var details *models.Food
+	var err error
+	if cancelable, ok := s.source.(contextSource); ok {
+		details, err = cancelable.GetFoodDetailsContext(ctx, id)
+	} else {
+		details, err = s.source.GetFoodDetails(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(s.Name(), cacheDetails, map[string]any{id: details}, true)
+	return details, nil
+}
+
+func (s *CachedSource) LookupBarcode(gtin string) (*models.Food, error) {
+	return s.LookupBarcodeContext(context.Background(), gtin)
+}
+
+// LookupBarcodeContext returns the cached product with a barcode. It fails
+// with models.ErrFoodNotFound when the wrapped source does not support
+// barcodes.
+func (s *CachedSource) LookupBarcodeContext(ctx context.Context, gtin string) (*models.Food, error) {
+	source, ok := s.source.(barcodeSource)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not support barcodes", models.ErrFoodNotFound, s.Name())
+	}
+
+	var food models.Food
+	if s.cache.get(s.Name(), cacheBarcode, gtin, &food) {
+		return &food, nil
+	}
+
+	var found *models.Food
+	var err error
+	if cancelable, ok := source.(contextBarcodeSource); ok {
+		found, err = cancelable.LookupBarcodeContext(ctx, gtin)
+	} else {
+		found, err = source.LookupBarcode(gtin)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(s.Name(), cacheBarcode, map[string]any{gtin: found}, true)
+	s.cache.put(s.Name(), cacheDetails, map[string]any{found.ID: found}, false)
+	return found, nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"fmt"
+	"nutritionapp/pkg/models"
+	"testing"
+	"time"
+)
+
+// countingSource is a food source counting the requests reaching it
+type countingSource struct {
+	searches int
+	details  map[string]int
+}
+
+func (s *countingSource) Name() string {
+	return "fdc"
+}
+
+func (s *countingSource) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	s.searches++
+	food := models.Food{ID: "1", Name: query.Text, Portions: []models.Portion{{Amount: 1, Description: "cup", GramWeight: 244}}}
+	return models.SearchResult{Foods: []models.Food{food}, TotalHits: 1}, nil
+}
+
+func (s *countingSource) GetFoodDetails(id string) (*models.Food, error) {
+	if s.details == nil {
+		s.details = make(map[string]int)
+	}
+	s.details[id]++
+	return &models.Food{ID: id, Name: "Food " + id, Calories: 100}, nil
+}
+
+// getDetails gets the details of foods through a cached source
+func getDetails(t *testing.T, cached *CachedSource, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		food, err := cached.GetFoodDetails(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if food.ID != id || food.Calories != 100 {
+			t.Fatalf("GetFoodDetails(%s) = %+v", id, food)
+		}
+	}
+}
+
+func TestFoodCacheHits(t *testing.T) {
+	s, _ := newTestDB(t)
+	source := &countingSource{}
+	cached := s.FoodCache(time.Hour, 0).Wrap(source)
+
+	getDetails(t, cached, "1", "1", "2")
+	if source.details["1"] != 1 || source.details["2"] != 1 {
+		t.Errorf("source details requests = %v, want one per food", source.details)
+	}
+
+	for _, text := range []string{"milk", "  MILK ", "milk"} {
+		if _, err := cached.SearchFoods(models.FoodQuery{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if source.searches != 1 {
+		t.Errorf("source searches = %d, want 1", source.searches)
+	}
+
+	stats, err := s.FoodCache(time.Hour, 0).Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 3 || stats.Searches != 1 || stats.Details != 2 || stats.Hits != 3 {
+		t.Errorf("stats = %+v, want 3 entries of 1 search and 2 details, 3 hits", stats)
+	}
+}
+
+func TestFoodCacheTTL(t *testing.T) {
+	s, _ := newTestDB(t)
+	source := &countingSource{}
+	cache := s.FoodCache(time.Hour, 0)
+	cached := cache.Wrap(source)
+
+	getDetails(t, cached, "1", "2")
+	old := time.Now().UTC().Add(-2 * time.Hour).Format(cacheTimeFormat)
+	if _, err := s.db.Exec(`UPDATE food_cache SET created_at = ? WHERE key = '1'`, old); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Expired != 1 {
+		t.Errorf("%d expired entries, want 1", stats.Expired)
+	}
+
+	getDetails(t, cached, "1", "2")
+	if source.details["1"] != 2 || source.details["2"] != 1 {
+		t.Errorf("source details requests = %v, want 2 for the expired food and 1 for the other", source.details)
+	}
+	if stats, _ := cache.Stats(); stats.Expired != 0 || stats.Entries != 2 {
+		t.Errorf("stats = %+v, want the expired entry replaced", stats)
+	}
+}
+
+func TestFoodCacheEviction(t *testing.T) {
+	s, _ := newTestDB(t)
+	source := &countingSource{}
+	cached := s.FoodCache(time.Hour, 2).Wrap(source)
+
+	// Using food 1 again makes food 2 the least recently used
+	getDetails(t, cached, "1", "2", "1", "3")
+
+	var keys []string
+	rows, err := s.db.Query(`SELECT key FROM food_cache ORDER BY key`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	if fmt.Sprint(keys) != "[1 3]" {
+		t.Errorf("cached foods = %v, want [1 3]", keys)
+	}
+
+	getDetails(t, cached, "2")
+	if source.details["2"] != 2 {
+		t.Errorf("source details requests of the evicted food = %d, want 2", source.details["2"])
+	}
+}
+
+func TestFoodCacheClear(t *testing.T) {
+	s, _ := newTestDB(t)
+	cache := s.FoodCache(time.Hour, 0)
+	getDetails(t, cache.Wrap(&countingSource{}), "1", "2")
+
+	cleared, err := cache.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleared != 2 {
+		t.Errorf("cleared %d entries, want 2", cleared)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("%d entries left", stats.Entries)
+	}
+}
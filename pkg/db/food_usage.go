@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strings"
+	"time"
+)
+
+// minFavoriteUses is the number of times a food that is not starred must
+// have been logged to be a favorite
+const minFavoriteUses = 2
+
+// foodUsageQuery selects the foods a user logged or starred, with their
+// usage. The user ID is its first two arguments. Meal times are stored with
+// their offset and a varying precision, so they are compared as julian days
+// rather than as text; last_used is the time of the latest meal, whose row
+// SQLite picks for the bare columns of a MAX query.
+const foodUsageQuery = `
+	WITH usage AS (
+		SELECT mi.food_id, COUNT(*) AS uses, MAX(julianday(m.time)) AS last_day, m.time AS last_used
+		FROM meal_items mi
+		JOIN meals m ON m.id = mi.meal_id
+		JOIN daily_logs dl ON dl.id = m.daily_log_id
+		WHERE dl.user_id = ?
+		GROUP BY mi.food_id
+	)
+	SELECT COALESCE(u.uses, 0), COALESCE(u.last_used, ''), fav.food_id IS NOT NULL, ` + foodColumns + `
+	FROM foods f
+	LEFT JOIN usage u ON u.food_id = f.id
+	LEFT JOIN favorite_foods fav ON fav.food_id = f.id AND fav.user_id = ?`
+
+// RecentFoods returns the foods a user logged, most recently logged first
+func (s *SQLiteDB) RecentFoods(userID int64, limit int) ([]models.FoodUsage, error) {
+	return s.queryFoodUsage(foodUsageQuery+`
+		WHERE u.food_id IS NOT NULL
+		ORDER BY u.last_day DESC, f.name
+		LIMIT ?
+	`, userID, userID, limit)
+}
+
+// FavoriteFoods returns the foods a user starred, then those logged at least
+// minFavoriteUses times, most logged first
+func (s *SQLiteDB) FavoriteFoods(userID int64, limit int) ([]models.FoodUsage, error) {
+	return s.queryFoodUsage(foodUsageQuery+`
+		WHERE fav.food_id IS NOT NULL OR u.uses >= ?
+		ORDER BY fav.food_id IS NOT NULL DESC, u.uses DESC, u.last_day DESC, f.name
+		LIMIT ?
+	`, userID, userID, minFavoriteUses, limit)
+}
+
+// SetFavorite stars or unstars a food for a user. A starred food is saved
+// along, as for meals.
+func (s *SQLiteDB) SetFavorite(userID int64, food *models.Food, starred bool) error {
+	if !starred {
+		_, err := s.db.Exec(`DELETE FROM favorite_foods WHERE user_id = ? AND food_id = ?`, userID, food.ID)
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveFood(tx, food); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO favorite_foods (user_id, food_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, food_id) DO NOTHING
+	`, userID, food.ID, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// StoredFood returns a food stored along with meals, favorites or recipes,
+// with its nutrients and portions. It fails with models.ErrFoodNotFound when
+// the food was never stored.
+func (s *SQLiteDB) StoredFood(id string) (*models.Food, error) {
+	var food models.Food
+	err := s.db.QueryRow(`SELECT `+foodColumns+` FROM foods f WHERE f.id = ?`, id).Scan(foodFields(&food)...)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s is not stored", models.ErrFoodNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nutrients, err := getNutrients(s.db, foodDetails, "?", id)
+	if err != nil {
+		return nil, err
+	}
+	portions, err := getPortions(s.db, foodDetails, "?", id)
+	if err != nil {
+		return nil, err
+	}
+	food.Nutrients = nutrients[id]
+	food.Portions = portions[id]
+	return &food, nil
+}
+
+// queryFoodUsage loads the foods selected by a foodUsageQuery, with their
+// nutrients and portions
+func (s *SQLiteDB) queryFoodUsage(query string, args ...any) ([]models.FoodUsage, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []models.FoodUsage
+	for rows.Next() {
+		var usage models.FoodUsage
+		var lastUsed string
+		if err := rows.Scan(append([]any{&usage.Uses, &lastUsed, &usage.Starred}, foodFields(&usage.Food)...)...); err != nil {
+			return nil, err
+		}
+		usage.LastUsed, _ = time.Parse(time.RFC3339Nano, lastUsed)
+		usages = append(usages, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(usages) == 0 {
+		return usages, nil
+	}
+
+	ids := make([]any, len(usages))
+	for i, usage := range usages {
+		ids[i] = usage.Food.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	nutrients, err := getNutrients(s.db, foodDetails, placeholders, ids...)
+	if err != nil {
+		return nil, err
+	}
+	portions, err := getPortions(s.db, foodDetails, placeholders, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range usages {
+		usages[i].Food.Nutrients = nutrients[usages[i].Food.ID]
+		usages[i].Food.Portions = portions[usages[i].Food.ID]
+	}
+	return usages, nil
+}
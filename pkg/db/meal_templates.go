@@ -0,0 +1,116 @@
+package db
+
+import (
+	"nutritionapp/pkg/models"
+	"time"
+)
+
+// templateFoodIDs selects the IDs of the foods of a user's meal templates
+const templateFoodIDs = `
+	SELECT ti.food_id
+	FROM meal_template_items ti
+	JOIN meal_templates t ON t.id = ti.template_id
+	WHERE t.user_id = ?`
+
+// SaveMealTemplate stores a meal template and sets its ID, replacing the
+// user's template of the same name, whatever its case. The foods of the meal
+// are saved along unless already stored: templates use their latest data.
+func (s *SQLiteDB) SaveMealTemplate(userID int64, template *models.MealTemplate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO meal_templates (user_id, name, meal_name, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, name) DO UPDATE SET
+			name = excluded.name,
+			meal_name = excluded.meal_name
+		RETURNING id
+	`, userID, template.Name, template.Meal.Name, time.Now().Format(time.RFC3339)).Scan(&template.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM meal_template_items WHERE template_id = ?`, template.ID); err != nil {
+		return err
+	}
+	for i, item := range template.Meal.Foods {
+		if err := ensureFood(tx, item.Food); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO meal_template_items (template_id, position, food_id, quantity)
+			VALUES (?, ?, ?, ?)
+		`, template.ID, i, item.Food.ID, item.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListMealTemplates retrieves a user's meal templates with their foods, by
+// name
+func (s *SQLiteDB) ListMealTemplates(userID int64) ([]models.MealTemplate, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, meal_name FROM meal_templates
+		WHERE user_id = ?
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.MealTemplate
+	byID := make(map[int64]int)
+	for rows.Next() {
+		template := models.MealTemplate{Meal: models.Meal{Foods: make([]models.FoodQuantity, 0)}}
+		if err := rows.Scan(&template.ID, &template.Name, &template.Meal.Name); err != nil {
+			return nil, err
+		}
+		byID[template.ID] = len(templates)
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nutrients, err := getNutrients(s.db, foodDetails, templateFoodIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	portions, err := getPortions(s.db, foodDetails, templateFoodIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	itemRows, err := s.db.Query(`
+		SELECT ti.template_id, ti.quantity, `+foodColumns+`
+		FROM meal_template_items ti
+		JOIN meal_templates t ON t.id = ti.template_id
+		JOIN foods f ON f.id = ti.food_id
+		WHERE t.user_id = ?
+		ORDER BY ti.template_id, ti.position
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var templateID int64
+		var quantity float64
+		var food models.Food
+		if err := itemRows.Scan(append([]any{&templateID, &quantity}, foodFields(&food)...)...); err != nil {
+			return nil, err
+		}
+		food.Nutrients = nutrients[food.ID]
+		food.Portions = portions[food.ID]
+		templates[byID[templateID]].Meal.AddFood(&food, quantity)
+	}
+	return templates, itemRows.Err()
+}
@@ -0,0 +1,735 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// migration is a single, ordered step in the evolution of the schema
+type migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+}
+
+// MigrationState describes whether a migration has been applied to a database
+type MigrationState struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   string
+}
+
+// migrations lists every schema change, in the order they must be applied.
+// Never edit or reorder an existing entry: append a new one instead.
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "create users and daily_logs tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS users (
+					id INTEGER PRIMARY KEY,
+					first_name TEXT NOT NULL,
+					last_name TEXT NOT NULL,
+					age INTEGER NOT NULL,
+					weight REAL NOT NULL,
+					height REAL NOT NULL,
+					gender TEXT NOT NULL,
+					goal TEXT NOT NULL
+				)`,
+				`CREATE TABLE IF NOT EXISTS daily_logs (
+					id INTEGER PRIMARY KEY,
+					date TEXT NOT NULL,
+					meals TEXT NOT NULL,
+					UNIQUE(date)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     2,
+		Description: "key daily_logs by user",
+		Up: func(tx *sql.Tx) error {
+			// Databases created before migrations were tracked may already
+			// have the column
+			exists, err := columnExists(tx, "daily_logs", "user_id")
+			if err != nil || exists {
+				return err
+			}
+
+			// The existing rows are assigned to the first user, created as a
+			// placeholder profile to edit when there is none. Its age, weight
+			// and height are 0, which the profile and reports treat as
+			// unknown.
+			return execAll(tx,
+				`INSERT INTO users (first_name, last_name, age, weight, height, gender, goal)
+				SELECT 'Default', 'User', 0, 0, 0, '', 'maintain'
+				WHERE EXISTS (SELECT 1 FROM daily_logs) AND NOT EXISTS (SELECT 1 FROM users)`,
+				`ALTER TABLE daily_logs RENAME TO daily_logs_old`,
+				`CREATE TABLE daily_logs (
+					id INTEGER PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id),
+					date TEXT NOT NULL,
+					meals TEXT NOT NULL,
+					UNIQUE(user_id, date)
+				)`,
+				`INSERT INTO daily_logs (user_id, date, meals)
+				SELECT (SELECT MIN(id) FROM users), date, meals FROM daily_logs_old`,
+				`DROP TABLE daily_logs_old`,
+			)
+		},
+	},
+	{
+		Version:     3,
+		Description: "move meals out of daily_logs into meals, meal_items and foods",
+		Up: func(tx *sql.Tx) error {
+			// Dropping the meals column needs ALTER TABLE DROP COLUMN, which
+			// SQLite supports since 3.35. The bundled SQLite is recent enough,
+			// but builds using the system library may not be.
+			if err := requireSQLite(tx, 3, 35); err != nil {
+				return err
+			}
+
+			err := execAll(tx,
+				`CREATE TABLE foods (
+					id TEXT PRIMARY KEY,
+					name TEXT NOT NULL,
+					calories REAL NOT NULL,
+					proteins REAL NOT NULL,
+					carbs REAL NOT NULL,
+					fats REAL NOT NULL,
+					fiber REAL NOT NULL
+				)`,
+				`CREATE TABLE meals (
+					id INTEGER PRIMARY KEY,
+					daily_log_id INTEGER NOT NULL REFERENCES daily_logs(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					name TEXT NOT NULL,
+					time TEXT NOT NULL
+				)`,
+				`CREATE INDEX meals_daily_log_id ON meals(daily_log_id)`,
+				`CREATE TABLE meal_items (
+					id INTEGER PRIMARY KEY,
+					meal_id INTEGER NOT NULL REFERENCES meals(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					food_id TEXT NOT NULL REFERENCES foods(id),
+					quantity REAL NOT NULL
+				)`,
+				`CREATE INDEX meal_items_meal_id ON meal_items(meal_id)`,
+				`CREATE INDEX meal_items_food_id ON meal_items(food_id)`,
+			)
+			if err != nil {
+				return err
+			}
+
+			if err := convertMealsJSON(tx); err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`ALTER TABLE daily_logs DROP COLUMN meals`)
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "add users.activity_level",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE users ADD COLUMN activity_level TEXT NOT NULL DEFAULT 'sedentary'`,
+			)
+		},
+	},
+	{
+		Version:     5,
+		Description: "turn users.goal into a goal type with parameters",
+		Up: func(tx *sql.Tx) error {
+			// Free-text goals are mapped to the closest goal type
+			return execAll(tx,
+				`ALTER TABLE users ADD COLUMN goal_rate REAL NOT NULL DEFAULT 0`,
+				`ALTER TABLE users ADD COLUMN goal_protein_per_kg REAL NOT NULL DEFAULT 0`,
+				`UPDATE users SET goal = CASE
+					WHEN lower(goal) LIKE '%loss%' OR lower(goal) LIKE '%lose%' THEN 'weight_loss'
+					WHEN lower(goal) LIKE '%gain%' OR lower(goal) LIKE '%muscle%' THEN 'muscle_gain'
+					ELSE 'maintenance'
+				END`,
+			)
+		},
+	},
+	{
+		Version:     6,
+		Description: "create weigh_ins table",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE weigh_ins (
+					id INTEGER PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					date TEXT NOT NULL,
+					weight REAL NOT NULL,
+					waist REAL NOT NULL DEFAULT 0,
+					hip REAL NOT NULL DEFAULT 0,
+					neck REAL NOT NULL DEFAULT 0,
+					body_fat REAL NOT NULL DEFAULT 0,
+					UNIQUE(user_id, date)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     7,
+		Description: "create food_nutrients table",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE food_nutrients (
+					food_id TEXT NOT NULL REFERENCES foods(id) ON DELETE CASCADE,
+					number TEXT NOT NULL,
+					name TEXT NOT NULL,
+					unit TEXT NOT NULL,
+					amount REAL NOT NULL,
+					PRIMARY KEY(food_id, number)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     8,
+		Description: "create the local food catalog tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE catalog_foods (
+					fdc_id INTEGER PRIMARY KEY,
+					data_type TEXT NOT NULL,
+					description TEXT NOT NULL
+				)`,
+				`CREATE TABLE catalog_nutrients (
+					fdc_id INTEGER NOT NULL REFERENCES catalog_foods(fdc_id) ON DELETE CASCADE,
+					number TEXT NOT NULL,
+					name TEXT NOT NULL,
+					unit TEXT NOT NULL,
+					amount REAL NOT NULL,
+					PRIMARY KEY(fdc_id, number)
+				)`,
+				`CREATE TABLE catalog_portions (
+					fdc_id INTEGER NOT NULL REFERENCES catalog_foods(fdc_id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					amount REAL NOT NULL,
+					description TEXT NOT NULL,
+					gram_weight REAL NOT NULL,
+					PRIMARY KEY(fdc_id, position)
+				)`,
+				// Full-text index of the descriptions, the docid being the fdc_id
+				`CREATE VIRTUAL TABLE catalog_foods_fts USING fts4(description)`,
+			)
+		},
+	},
+	{
+		Version:     9,
+		Description: "create food_cache table",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE food_cache (
+					source TEXT NOT NULL,
+					kind TEXT NOT NULL,
+					key TEXT NOT NULL,
+					data BLOB NOT NULL,
+					created_at TEXT NOT NULL,
+					used_at TEXT NOT NULL,
+					hits INTEGER NOT NULL DEFAULT 0,
+					PRIMARY KEY(source, kind, key)
+				)`,
+				`CREATE INDEX idx_food_cache_used_at ON food_cache(used_at)`,
+			)
+		},
+	},
+	{
+		Version:     10,
+		Description: "add food data types, brand owners and servings",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE foods ADD COLUMN data_type TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE foods ADD COLUMN brand_owner TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE foods ADD COLUMN serving_size REAL NOT NULL DEFAULT 0`,
+				`ALTER TABLE foods ADD COLUMN serving_text TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE catalog_foods ADD COLUMN brand_owner TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE catalog_foods ADD COLUMN serving_size REAL NOT NULL DEFAULT 0`,
+				`ALTER TABLE catalog_foods ADD COLUMN serving_text TEXT NOT NULL DEFAULT ''`,
+				// The catalog used the bulk download names of the data types
+				`UPDATE catalog_foods SET data_type = CASE data_type
+					WHEN 'foundation_food' THEN 'Foundation'
+					WHEN 'sr_legacy_food' THEN 'SR Legacy'
+					WHEN 'survey_fndds_food' THEN 'Survey (FNDDS)'
+					WHEN 'branded_food' THEN 'Branded'
+					ELSE data_type
+				END`,
+				`CREATE INDEX idx_catalog_foods_data_type ON catalog_foods(data_type)`,
+			)
+		},
+	},
+	{
+		Version:     11,
+		Description: "add food barcodes",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`ALTER TABLE foods ADD COLUMN gtin TEXT NOT NULL DEFAULT ''`,
+				`ALTER TABLE catalog_foods ADD COLUMN gtin TEXT NOT NULL DEFAULT ''`,
+				`CREATE INDEX idx_catalog_foods_gtin ON catalog_foods(gtin) WHERE gtin != ''`,
+			)
+		},
+	},
+	{
+		Version:     12,
+		Description: "create food_portions table",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE food_portions (
+					food_id TEXT NOT NULL REFERENCES foods(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					amount REAL NOT NULL,
+					description TEXT NOT NULL,
+					gram_weight REAL NOT NULL,
+					PRIMARY KEY(food_id, position)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     13,
+		Description: "create the custom food tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE custom_foods (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL,
+					brand_owner TEXT NOT NULL DEFAULT '',
+					serving_size REAL NOT NULL DEFAULT 0,
+					serving_text TEXT NOT NULL DEFAULT '',
+					created_at TEXT NOT NULL
+				)`,
+				`CREATE TABLE custom_food_nutrients (
+					food_id INTEGER NOT NULL REFERENCES custom_foods(id) ON DELETE CASCADE,
+					number TEXT NOT NULL,
+					name TEXT NOT NULL,
+					unit TEXT NOT NULL,
+					amount REAL NOT NULL,
+					PRIMARY KEY(food_id, number)
+				)`,
+				`CREATE TABLE custom_food_portions (
+					food_id INTEGER NOT NULL REFERENCES custom_foods(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					amount REAL NOT NULL,
+					description TEXT NOT NULL,
+					gram_weight REAL NOT NULL,
+					PRIMARY KEY(food_id, position)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     14,
+		Description: "create the recipe tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE recipes (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT NOT NULL,
+					servings REAL NOT NULL,
+					yield_weight REAL NOT NULL DEFAULT 0,
+					created_at TEXT NOT NULL
+				)`,
+				`CREATE TABLE recipe_ingredients (
+					recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					food_id TEXT NOT NULL REFERENCES foods(id),
+					quantity REAL NOT NULL,
+					PRIMARY KEY(recipe_id, position)
+				)`,
+				`CREATE INDEX recipe_ingredients_food_id ON recipe_ingredients(food_id)`,
+			)
+		},
+	},
+	{
+		Version:     15,
+		Description: "create favorite_foods table",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE favorite_foods (
+					user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					food_id TEXT NOT NULL REFERENCES foods(id),
+					created_at TEXT NOT NULL,
+					PRIMARY KEY(user_id, food_id)
+				)`,
+			)
+		},
+	},
+	{
+		Version:     16,
+		Description: "create the meal template tables",
+		Up: func(tx *sql.Tx) error {
+			return execAll(tx,
+				`CREATE TABLE meal_templates (
+					id INTEGER PRIMARY KEY,
+					user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+					name TEXT NOT NULL COLLATE NOCASE,
+					meal_name TEXT NOT NULL,
+					created_at TEXT NOT NULL,
+					UNIQUE(user_id, name)
+				)`,
+				`CREATE TABLE meal_template_items (
+					template_id INTEGER NOT NULL REFERENCES meal_templates(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					food_id TEXT NOT NULL REFERENCES foods(id),
+					quantity REAL NOT NULL,
+					PRIMARY KEY(template_id, position)
+				)`,
+				`CREATE INDEX meal_template_items_food_id ON meal_template_items(food_id)`,
+			)
+		},
+	},
+	{
+		Version:     17,
+		Description: "give the catalog foods their FDC food IDs",
+		Up: func(tx *sql.Tx) error {
+			// Foods of the local catalog were stored as "local_<fdc id>", and
+			// the same food found with the API as "fdc_<fdc id>". The foods
+			// stored both ways are merged into the latter. References are
+			// only checked on commit, once they all point to the new IDs.
+			local := func(column string) string { return `substr(` + column + `, 1, 6) = 'local_'` }
+			fdcID := func(column string) string { return `'fdc_' || substr(` + column + `, 7)` }
+			statements := []string{
+				`PRAGMA defer_foreign_keys = ON`,
+				`DELETE FROM favorite_foods WHERE ` + local("food_id") + ` AND EXISTS (
+					SELECT 1 FROM favorite_foods fav
+					WHERE fav.user_id = favorite_foods.user_id AND fav.food_id = ` + fdcID("favorite_foods.food_id") + `
+				)`,
+				`DELETE FROM food_nutrients WHERE ` + local("food_id") + ` AND ` + fdcID("food_id") + ` IN (SELECT id FROM foods)`,
+				`DELETE FROM food_portions WHERE ` + local("food_id") + ` AND ` + fdcID("food_id") + ` IN (SELECT id FROM foods)`,
+				`DELETE FROM foods WHERE ` + local("id") + ` AND ` + fdcID("id") + ` IN (SELECT id FROM foods)`,
+				`UPDATE foods SET id = ` + fdcID("id") + ` WHERE ` + local("id"),
+			}
+			for _, table := range []string{"food_nutrients", "food_portions", "meal_items", "recipe_ingredients", "favorite_foods", "meal_template_items"} {
+				statements = append(statements, `UPDATE `+table+` SET food_id = `+fdcID("food_id")+` WHERE `+local("food_id"))
+			}
+			return execAll(tx, statements...)
+		},
+	},
+	{
+		Version:     18,
+		Description: "keep the food data meal items were logged with in food_versions",
+		Up: func(tx *sql.Tx) error {
+			// The foods logged so far get a version with their current data.
+			// Their fingerprint is unknown, so new logs never share them.
+			return execAll(tx,
+				`CREATE TABLE food_versions (
+					id INTEGER PRIMARY KEY,
+					food_id TEXT NOT NULL REFERENCES foods(id),
+					fingerprint TEXT NOT NULL,
+					name TEXT NOT NULL,
+					calories REAL NOT NULL,
+					proteins REAL NOT NULL,
+					carbs REAL NOT NULL,
+					fats REAL NOT NULL,
+					fiber REAL NOT NULL,
+					data_type TEXT NOT NULL DEFAULT '',
+					brand_owner TEXT NOT NULL DEFAULT '',
+					serving_size REAL NOT NULL DEFAULT 0,
+					serving_text TEXT NOT NULL DEFAULT '',
+					gtin TEXT NOT NULL DEFAULT ''
+				)`,
+				`CREATE INDEX food_versions_fingerprint ON food_versions(food_id, fingerprint)`,
+				`CREATE TABLE food_version_nutrients (
+					version_id INTEGER NOT NULL REFERENCES food_versions(id) ON DELETE CASCADE,
+					number TEXT NOT NULL,
+					name TEXT NOT NULL,
+					unit TEXT NOT NULL,
+					amount REAL NOT NULL,
+					PRIMARY KEY(version_id, number)
+				)`,
+				`CREATE TABLE food_version_portions (
+					version_id INTEGER NOT NULL REFERENCES food_versions(id) ON DELETE CASCADE,
+					position INTEGER NOT NULL,
+					amount REAL NOT NULL,
+					description TEXT NOT NULL,
+					gram_weight REAL NOT NULL,
+					PRIMARY KEY(version_id, position)
+				)`,
+				`ALTER TABLE meal_items ADD COLUMN food_version_id INTEGER REFERENCES food_versions(id)`,
+				`INSERT INTO food_versions (food_id, fingerprint, name, calories, proteins, carbs, fats, fiber, data_type, brand_owner, serving_size, serving_text, gtin)
+				SELECT id, '', name, calories, proteins, carbs, fats, fiber, data_type, brand_owner, serving_size, serving_text, gtin
+				FROM foods WHERE id IN (SELECT food_id FROM meal_items)`,
+				`INSERT INTO food_version_nutrients (version_id, number, name, unit, amount)
+				SELECT v.id, fn.number, fn.name, fn.unit, fn.amount
+				FROM food_versions v JOIN food_nutrients fn ON fn.food_id = v.food_id`,
+				`INSERT INTO food_version_portions (version_id, position, amount, description, gram_weight)
+				SELECT v.id, fp.position, fp.amount, fp.description, fp.gram_weight
+				FROM food_versions v JOIN food_portions fp ON fp.food_id = v.food_id`,
+				`UPDATE meal_items SET food_version_id = (SELECT id FROM food_versions WHERE food_id = meal_items.food_id)`,
+				`CREATE INDEX meal_items_food_version_id ON meal_items(food_version_id)`,
+			)
+		},
+	},
+}
+
+// convertMealsJSON copies the meals stored as JSON in daily_logs.meals into
+// the meals, meal_items and foods tables
+func convertMealsJSON(tx *sql.Tx) error {
+	// Frozen copy of the JSON layout daily_logs.meals was written with
+	type legacyMeal struct {
+		Name  string
+		Time  time.Time
+		Foods []struct {
+			Food *struct {
+				ID       string
+				Name     string
+				Calories float64
+				Proteins float64
+				Carbs    float64
+				Fats     float64
+				Fiber    float64
+			}
+			Quantity float64
+		}
+	}
+
+	rows, err := tx.Query(`SELECT id, meals FROM daily_logs`)
+	if err != nil {
+		return err
+	}
+
+	logs := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var mealsJSON string
+		if err := rows.Scan(&id, &mealsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		logs[id] = mealsJSON
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for logID, mealsJSON := range logs {
+		var meals []legacyMeal
+		if err := json.Unmarshal([]byte(mealsJSON), &meals); err != nil {
+			return fmt.Errorf("daily log %d has invalid meals: %v", logID, err)
+		}
+
+		for i, meal := range meals {
+			result, err := tx.Exec(`
+				INSERT INTO meals (daily_log_id, position, name, time)
+				VALUES (?, ?, ?, ?)
+			`, logID, i, meal.Name, meal.Time.Format(time.RFC3339Nano))
+			if err != nil {
+				return err
+			}
+			mealID, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+
+			for j, item := range meal.Foods {
+				if item.Food == nil {
+					continue
+				}
+				f := item.Food
+				_, err := tx.Exec(`
+					INSERT INTO foods (id, name, calories, proteins, carbs, fats, fiber)
+					VALUES (?, ?, ?, ?, ?, ?, ?)
+					ON CONFLICT(id) DO NOTHING
+				`, f.ID, f.Name, f.Calories, f.Proteins, f.Carbs, f.Fats, f.Fiber)
+				if err != nil {
+					return err
+				}
+
+				_, err = tx.Exec(`
+					INSERT INTO meal_items (meal_id, position, food_id, quantity)
+					VALUES (?, ?, ?, ?)
+				`, mealID, j, f.ID, item.Quantity)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrate applies every pending migration, each in its own transaction,
+// and returns the ones that were applied
+func migrate(db *sql.DB) ([]MigrationState, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationState
+	for _, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Description, err)
+		}
+		applied = append(applied, MigrationState{
+			Version:     m.Version,
+			Description: m.Description,
+			Applied:     true,
+		})
+	}
+	return applied, nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO schema_version (version, description, applied_at)
+		VALUES (?, ?, ?)
+	`, m.Version, m.Description, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func pendingMigrations(db *sql.DB) ([]migration, error) {
+	states, err := migrationStates(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for i, state := range states {
+		if !state.Applied {
+			pending = append(pending, migrations[i])
+		}
+	}
+	return pending, nil
+}
+
+// migrationStates reports the state of every known migration, in order.
+// Every migration is pending when the database has no schema_version table.
+func migrationStates(db *sql.DB) ([]MigrationState, error) {
+	var tables int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'
+	`).Scan(&tables)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[int]string)
+	if tables > 0 {
+		if err := readSchemaVersion(db, appliedAt); err != nil {
+			return nil, err
+		}
+	}
+	return newMigrationStates(appliedAt), nil
+}
+
+// newMigrationStates lists every known migration, the applied ones having
+// their application time
+func newMigrationStates(appliedAt map[int]string) []MigrationState {
+	var states []MigrationState
+	for _, m := range migrations {
+		at, applied := appliedAt[m.Version]
+		states = append(states, MigrationState{
+			Version:     m.Version,
+			Description: m.Description,
+			Applied:     applied,
+			AppliedAt:   at,
+		})
+	}
+	return states
+}
+
+// readSchemaVersion reads when each applied migration was applied
+func readSchemaVersion(db *sql.DB, appliedAt map[int]string) error {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return err
+		}
+		appliedAt[version] = at
+	}
+	return rows.Err()
+}
+
+// GetMigrationStatus reports the state of every known migration for the
+// database at path, without applying any of them. The database is opened
+// read-only, and every migration is pending when it does not exist yet.
+func GetMigrationStatus(path string) ([]MigrationState, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return newMigrationStates(nil), nil
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return migrationStates(db)
+}
+
+func execAll(tx *sql.Tx, statements ...string) error {
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireSQLite fails unless the SQLite library is at least major.minor
+func requireSQLite(tx *sql.Tx, major, minor int) error {
+	var version string
+	if err := tx.QueryRow(`SELECT sqlite_version()`).Scan(&version); err != nil {
+		return err
+	}
+	var gotMajor, gotMinor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &gotMajor, &gotMinor); err != nil {
+		return fmt.Errorf("unknown SQLite version %q", version)
+	}
+	if gotMajor < major || gotMajor == major && gotMinor < minor {
+		return fmt.Errorf("SQLite %d.%d or later is required, found %s", major, minor, version)
+	}
+	return nil
+}
+
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?
+	`, table, column).Scan(&count)
+	return count > 0, err
+}
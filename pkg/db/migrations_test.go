@@ -0,0 +1,368 @@
+package db
+
+import (
+	"database/sql"
+	"nutritionapp/pkg/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// createBaselineDB creates a database with the schema the app had before
+// migrations were tracked, holding the given daily logs and users
+func createBaselineDB(t *testing.T, logs map[string]string, users int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			first_name TEXT NOT NULL,
+			last_name TEXT NOT NULL,
+			age INTEGER NOT NULL,
+			weight REAL NOT NULL,
+			height REAL NOT NULL,
+			gender TEXT NOT NULL,
+			goal TEXT NOT NULL
+		)`,
+		`CREATE TABLE daily_logs (
+			id INTEGER PRIMARY KEY,
+			date TEXT NOT NULL,
+			meals TEXT NOT NULL,
+			UNIQUE(date)
+		)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < users; i++ {
+		_, err := db.Exec(`
+			INSERT INTO users (first_name, last_name, age, weight, height, gender, goal)
+			VALUES ('Jane', 'Doe', 30, 60, 165, 'female', 'maintain')
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for date, meals := range logs {
+		if _, err := db.Exec(`INSERT INTO daily_logs (date, meals) VALUES (?, ?)`, date, meals); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+const baselineMeals = `[{"Name":"Breakfast","Time":"2024-03-01T08:00:00Z","Foods":[` +
+	`{"Food":{"ID":"171287","Name":"Egg","Calories":143,"Proteins":12.6,"Carbs":0.7,"Fats":9.5,"Fiber":0},"Quantity":100},` +
+	`{"Food":{"ID":"173944","Name":"Banana","Calories":89,"Proteins":1.1,"Carbs":22.8,"Fats":0.3,"Fiber":2.6},"Quantity":120}]}]`
+
+func TestMigrateBaseline(t *testing.T) {
+	tests := []struct {
+		name      string
+		logs      map[string]string
+		users     int
+		wantUsers int
+		wantMeals int
+		wantItems int
+	}{
+		{"empty", nil, 0, 0, 0, 0},
+		{"logs without users", map[string]string{"2024-03-01": baselineMeals, "2024-03-02": "[]"}, 0, 1, 1, 2},
+		{"logs with a user", map[string]string{"2024-03-01": baselineMeals}, 1, 1, 1, 2},
+		{"users without logs", nil, 2, 2, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createBaselineDB(t, tt.logs, tt.users)
+			s, err := NewSQLiteDB(path)
+			if err != nil {
+				t.Fatalf("NewSQLiteDB() error = %v", err)
+			}
+			defer s.db.Close()
+
+			counts := map[string]int{
+				"users":      tt.wantUsers,
+				"daily_logs": len(tt.logs),
+				"meals":      tt.wantMeals,
+				"meal_items": tt.wantItems,
+			}
+			for table, want := range counts {
+				var got int
+				if err := s.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s rows = %d, want %d", table, got, want)
+				}
+			}
+
+			if len(tt.logs) > 0 {
+				day, _ := time.Parse("2006-01-02", "2024-03-01")
+				var userID int64
+				if err := s.db.QueryRow(`SELECT MIN(id) FROM users`).Scan(&userID); err != nil {
+					t.Fatal(err)
+				}
+				if user := s.GetUser(userID); tt.users == 0 && user.HasMeasurements() {
+					t.Errorf("placeholder user = %+v, want unknown measurements", user)
+				}
+				dailyLog := s.GetDailyLog(userID, day)
+				if len(dailyLog.Meals) != 1 || len(dailyLog.Meals[0].Foods) != 2 {
+					t.Errorf("GetDailyLog() = %d meals, want 1 meal of 2 foods", len(dailyLog.Meals))
+				}
+			}
+		})
+	}
+}
+
+func TestGetMigrationStatusReadOnly(t *testing.T) {
+	path := createBaselineDB(t, map[string]string{"2024-03-01": baselineMeals}, 0)
+
+	states, err := GetMigrationStatus(path)
+	if err != nil {
+		t.Fatalf("GetMigrationStatus() error = %v", err)
+	}
+	if len(states) != len(migrations) {
+		t.Fatalf("GetMigrationStatus() = %d states, want %d", len(states), len(migrations))
+	}
+	for _, state := range states {
+		if state.Applied {
+			t.Errorf("migration %d is applied, want pending", state.Version)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_version'`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Error("GetMigrationStatus() created the schema_version table")
+	}
+}
+
+func TestGetMigrationStatusMissingDB(t *testing.T) {
+	states, err := GetMigrationStatus(filepath.Join(t.TempDir(), "missing.db"))
+	if err != nil {
+		t.Fatalf("GetMigrationStatus() error = %v", err)
+	}
+	for _, state := range states {
+		if state.Applied {
+			t.Errorf("migration %d is applied, want pending", state.Version)
+		}
+	}
+}
+
+// migrateTo creates a database with the migrations up to a version applied,
+// as an older release left it, and returns its path and connection
+func migrateTo(t *testing.T, version int) (string, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE schema_version (version INTEGER PRIMARY KEY, description TEXT NOT NULL, applied_at TEXT NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations[:version] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("migration %d: %v", m.Version, err)
+		}
+	}
+	return path, db
+}
+
+// upgrade applies the pending migrations of a database
+func upgrade(t *testing.T, path string) *SQLiteDB {
+	t.Helper()
+	s, err := NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteDB() error = %v", err)
+	}
+	t.Cleanup(func() { s.db.Close() })
+	return s
+}
+
+// execAllT runs statements, failing the test on the first error
+func execAllT(t *testing.T, db *sql.DB, statements ...string) {
+	t.Helper()
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestMigrateFromEveryVersion(t *testing.T) {
+	for version := 1; version < len(migrations); version++ {
+		path, db := migrateTo(t, version)
+		db.Close()
+		s := upgrade(t, path)
+
+		var latest int
+		if err := s.db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&latest); err != nil {
+			t.Fatal(err)
+		}
+		if latest != len(migrations) {
+			t.Errorf("upgrading from version %d reached version %d, want %d", version, latest, len(migrations))
+		}
+	}
+}
+
+func TestMigrateUserGoals(t *testing.T) {
+	path, db := migrateTo(t, 3)
+	execAllT(t, db, `INSERT INTO users (first_name, last_name, age, weight, height, gender, goal) VALUES
+		('A', 'A', 30, 60, 165, 'female', 'Lose 5 kg'),
+		('B', 'B', 30, 80, 180, 'male', 'build muscle'),
+		('C', 'C', 30, 70, 170, 'male', 'stay healthy')`)
+	db.Close()
+	s := upgrade(t, path)
+
+	users, err := s.ListUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.GoalType{models.GoalWeightLoss, models.GoalMuscleGain, models.GoalMaintenance}
+	if len(users) != len(want) {
+		t.Fatalf("%d users, want %d", len(users), len(want))
+	}
+	for i, user := range users {
+		if user.Goal.Type != want[i] || user.ActivityLevel != models.ActivitySedentary {
+			t.Errorf("user %s goal %q, activity %q, want %q, sedentary", user.FirstName, user.Goal.Type, user.ActivityLevel, want[i])
+		}
+	}
+}
+
+func TestMigrateCatalogDataTypes(t *testing.T) {
+	path, db := migrateTo(t, 9)
+	execAllT(t, db, `INSERT INTO catalog_foods (fdc_id, data_type, description) VALUES
+		(1, 'foundation_food', 'Milk'),
+		(2, 'sr_legacy_food', 'Bread'),
+		(3, 'branded_food', 'Granola bar'),
+		(4, 'survey_fndds_food', 'Soup')`)
+	db.Close()
+	s := upgrade(t, path)
+
+	want := map[int64]models.DataType{1: models.DataTypeFoundation, 2: models.DataTypeSRLegacy, 3: models.DataTypeBranded, 4: models.DataTypeSurvey}
+	for fdcID, dataType := range want {
+		var got models.DataType
+		if err := s.db.QueryRow(`SELECT data_type FROM catalog_foods WHERE fdc_id = ?`, fdcID).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != dataType {
+			t.Errorf("food %d data type = %q, want %q", fdcID, got, dataType)
+		}
+	}
+}
+
+func TestMigrateLocalFoodIDs(t *testing.T) {
+	path, db := migrateTo(t, 16)
+	execAllT(t, db,
+		`INSERT INTO users (id, first_name, last_name, age, weight, height, gender, goal) VALUES (1, 'Jane', 'Doe', 30, 60, 165, 'female', 'maintenance')`,
+		`INSERT INTO daily_logs (id, user_id, date) VALUES (1, 1, '2024-03-01')`,
+		`INSERT INTO meals (id, daily_log_id, position, name, time) VALUES (1, 1, 0, 'Lunch', '2024-03-01T12:00:00Z')`,
+		// Milk was stored both from the catalog and the API, bread only from the catalog
+		`INSERT INTO foods (id, name, calories, proteins, carbs, fats, fiber) VALUES
+			('local_1', 'Milk', 60, 3, 5, 3, 0),
+			('fdc_1', 'Milk', 61, 3, 5, 3, 0),
+			('local_2', 'Bread', 250, 9, 49, 3, 7)`,
+		`INSERT INTO food_nutrients (food_id, number, name, unit, amount) VALUES
+			('local_1', '307', 'Sodium', 'mg', 40),
+			('local_2', '307', 'Sodium', 'mg', 400)`,
+		`INSERT INTO meal_items (meal_id, position, food_id, quantity) VALUES (1, 0, 'local_1', 200), (1, 1, 'local_2', 50)`,
+		`INSERT INTO favorite_foods (user_id, food_id, created_at) VALUES (1, 'local_1', ''), (1, 'fdc_1', ''), (1, 'local_2', '')`,
+	)
+	db.Close()
+	s := upgrade(t, path)
+
+	var localIDs int
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM foods WHERE id LIKE 'local\_%' ESCAPE '\')
+			+ (SELECT COUNT(*) FROM meal_items WHERE food_id LIKE 'local\_%' ESCAPE '\')
+			+ (SELECT COUNT(*) FROM favorite_foods WHERE food_id LIKE 'local\_%' ESCAPE '\')
+	`).Scan(&localIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if localIDs != 0 {
+		t.Errorf("%d rows still use local IDs", localIDs)
+	}
+
+	dailyLog := s.GetDailyLog(1, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local))
+	if len(dailyLog.Meals) != 1 || len(dailyLog.Meals[0].Foods) != 2 {
+		t.Fatalf("GetDailyLog() = %+v, want 1 meal of 2 foods", dailyLog.Meals)
+	}
+	milk, bread := dailyLog.Meals[0].Foods[0].Food, dailyLog.Meals[0].Foods[1].Food
+	if milk.ID != "fdc_1" || milk.Calories != 61 {
+		t.Errorf("milk = %s at %v kcal, want the API food fdc_1 at 61 kcal", milk.ID, milk.Calories)
+	}
+	if bread.ID != "fdc_2" || bread.Nutrients["307"].Amount != 400 {
+		t.Errorf("bread = %s with %v mg of sodium, want fdc_2 with 400 mg", bread.ID, bread.Nutrients["307"].Amount)
+	}
+
+	favorites, err := s.FavoriteFoods(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites) != 2 {
+		t.Errorf("%d favorites, want milk and bread", len(favorites))
+	}
+}
+
+func TestMigrateFoodVersions(t *testing.T) {
+	path, db := migrateTo(t, 17)
+	execAllT(t, db,
+		`INSERT INTO users (id, first_name, last_name, age, weight, height, gender, goal) VALUES (1, 'Jane', 'Doe', 30, 60, 165, 'female', 'maintenance')`,
+		`INSERT INTO daily_logs (id, user_id, date) VALUES (1, 1, '2024-03-01')`,
+		`INSERT INTO meals (id, daily_log_id, position, name, time) VALUES (1, 1, 0, 'Lunch', '2024-03-01T12:00:00Z')`,
+		`INSERT INTO foods (id, name, calories, proteins, carbs, fats, fiber) VALUES ('fdc_1', 'Bread', 250, 9, 49, 3, 7)`,
+		`INSERT INTO food_nutrients (food_id, number, name, unit, amount) VALUES ('fdc_1', '307', 'Sodium', 'mg', 400)`,
+		`INSERT INTO food_portions (food_id, position, amount, description, gram_weight) VALUES ('fdc_1', 0, 1, 'slice', 30)`,
+		`INSERT INTO meal_items (meal_id, position, food_id, quantity) VALUES (1, 0, 'fdc_1', 60)`,
+	)
+	db.Close()
+	s := upgrade(t, path)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	bread := loggedFood(t, s, 1, day)
+	if bread.Calories != 250 || bread.Nutrients["307"].Amount != 400 || len(bread.Portions) != 1 || bread.Version == 0 {
+		t.Fatalf("migrated log food = %+v, want bread with its sodium, portion and version", bread)
+	}
+
+	// Logging the same data again gets a new version, as the fingerprint of
+	// the migrated one is unknown
+	again := *bread
+	again.Version = 0
+	logFood(t, s, 1, day.AddDate(0, 0, 1), again)
+	var versions int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM food_versions WHERE food_id = 'fdc_1'`).Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != 2 {
+		t.Errorf("%d versions of the food, want 2", versions)
+	}
+
+	// Changing the food leaves the migrated log alone
+	changed := again
+	changed.Calories = 260
+	logFood(t, s, 1, day.AddDate(0, 0, 2), changed)
+	if past := loggedFood(t, s, 1, day); past.Calories != 250 {
+		t.Errorf("migrated log food = %v kcal, want 250", past.Calories)
+	}
+}
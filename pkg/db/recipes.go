@@ -0,0 +1,266 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recipes are the dishes made of several foods, shared by every user as
+// custom foods are. It is a food source, whose foods describe a recipe per
+// 100g of the cooked dish.
+type Recipes struct {
+	db *sql.DB
+}
+
+// Recipes returns the recipes stored in the database
+func (s *SQLiteDB) Recipes() *Recipes {
+	return &Recipes{db: s.db}
+}
+
+// Name identifies the recipes as a food source
+func (r *Recipes) Name() string {
+	return "recipe"
+}
+
+// recipeIngredientIDs selects the IDs of the ingredients of a recipe
+const recipeIngredientIDs = `SELECT food_id FROM recipe_ingredients WHERE recipe_id = ?`
+
+// SearchFoods finds a page of the recipes whose name contains every term of
+// the query, whatever its data types. By relevance, shortest names come first.
+func (r *Recipes) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	var result models.SearchResult
+	query = query.Normalize()
+
+	terms := strings.Fields(strings.ToLower(query.Text))
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	var conditions []string
+	var args []any
+	for _, term := range terms {
+		conditions = append(conditions, `lower(name) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	where := ` WHERE ` + strings.Join(conditions, " AND ")
+
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM recipes`+where, args...).Scan(&result.TotalHits); err != nil {
+		return result, err
+	}
+
+	order := `length(name), id`
+	if query.Sort == models.SortName {
+		order = `name COLLATE NOCASE, id`
+	}
+	args = append(args, query.PageSize, (query.Page-1)*query.PageSize)
+	recipes, err := r.queryRecipes(`
+		SELECT id, name, servings, yield_weight
+		FROM recipes`+where+`
+		ORDER BY `+order+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	for _, recipe := range recipes {
+		result.Foods = append(result.Foods, *recipe.ToFood())
+	}
+	return result, err
+}
+
+// GetFoodDetails describes a recipe as a food
+func (r *Recipes) GetFoodDetails(id string) (*models.Food, error) {
+	recipe, err := r.GetRecipe(id)
+	if err != nil {
+		return nil, err
+	}
+	return recipe.ToFood(), nil
+}
+
+// GetRecipe loads a recipe with its ingredients
+func (r *Recipes) GetRecipe(id string) (*models.Recipe, error) {
+	recipes, err := r.queryRecipes(`
+		SELECT id, name, servings, yield_weight
+		FROM recipes
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipes) == 0 {
+		return nil, fmt.Errorf("%w: no recipe %s", models.ErrFoodNotFound, id)
+	}
+	return &recipes[0], nil
+}
+
+// ListRecipes returns every recipe, by name
+func (r *Recipes) ListRecipes() ([]models.Recipe, error) {
+	return r.queryRecipes(`
+		SELECT id, name, servings, yield_weight
+		FROM recipes
+		ORDER BY name COLLATE NOCASE, id
+	`)
+}
+
+// CreateRecipe stores a new recipe and sets its ID. The foods of its
+// ingredients are saved along, as for meals.
+func (r *Recipes) CreateRecipe(recipe *models.Recipe) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO recipes (name, servings, yield_weight, created_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, recipe.Name, recipe.Servings, recipe.YieldWeight, time.Now().Format(time.RFC3339)).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	if err := saveIngredients(tx, id, recipe.Ingredients); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	recipe.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+// UpdateRecipe replaces a recipe. As for custom foods, the food stored for it
+// is updated too, while the meal items already logged keep the data they were
+// logged with.
+func (r *Recipes) UpdateRecipe(recipe *models.Recipe) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE recipes SET name = ?, servings = ?, yield_weight = ?
+		WHERE id = ?
+	`, recipe.Name, recipe.Servings, recipe.YieldWeight, recipe.ID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return fmt.Errorf("%w: no recipe %s", models.ErrFoodNotFound, recipe.ID)
+	}
+
+	id, err := strconv.ParseInt(recipe.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := saveIngredients(tx, id, recipe.Ingredients); err != nil {
+		return err
+	}
+
+	stored := recipe.ToFood()
+	stored.ID = r.Name() + "_" + recipe.ID
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM foods WHERE id = ?`, stored.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		if err := saveFood(tx, stored); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// saveIngredients replaces the ingredients of a recipe
+func saveIngredients(tx *sql.Tx, recipeID int64, ingredients []models.FoodQuantity) error {
+	if _, err := tx.Exec(`DELETE FROM recipe_ingredients WHERE recipe_id = ?`, recipeID); err != nil {
+		return err
+	}
+
+	for i, item := range ingredients {
+		if err := saveFood(tx, item.Food); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO recipe_ingredients (recipe_id, position, food_id, quantity)
+			VALUES (?, ?, ?, ?)
+		`, recipeID, i, item.Food.ID, item.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryRecipes loads the recipes selected by a query, with their ingredients
+func (r *Recipes) queryRecipes(query string, args ...any) ([]models.Recipe, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipes []models.Recipe
+	for rows.Next() {
+		var id int64
+		var recipe models.Recipe
+		if err := rows.Scan(&id, &recipe.Name, &recipe.Servings, &recipe.YieldWeight); err != nil {
+			return nil, err
+		}
+		recipe.ID = strconv.FormatInt(id, 10)
+		recipes = append(recipes, recipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range recipes {
+		if recipes[i].Ingredients, err = r.getIngredients(recipes[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return recipes, nil
+}
+
+// getIngredients loads the ingredients of a recipe, in order
+func (r *Recipes) getIngredients(recipeID string) ([]models.FoodQuantity, error) {
+	nutrients, err := getNutrients(r.db, foodDetails, recipeIngredientIDs, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	portions, err := getPortions(r.db, foodDetails, recipeIngredientIDs, recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT ri.quantity, `+foodColumns+`
+		FROM recipe_ingredients ri
+		JOIN foods f ON f.id = ri.food_id
+		WHERE ri.recipe_id = ?
+		ORDER BY ri.position
+	`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ingredients := make([]models.FoodQuantity, 0)
+	for rows.Next() {
+		var quantity float64
+		var food models.Food
+		if err := rows.Scan(append([]any{&quantity}, foodFields(&food)...)...); err != nil {
+			return nil, err
+		}
+		food.Nutrients = nutrients[food.ID]
+		food.Portions = portions[food.ID]
+		ingredients = append(ingredients, models.FoodQuantity{Food: &food, Quantity: quantity})
+	}
+	return ingredients, rows.Err()
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"errors"
+	"nutritionapp/pkg/models"
+	"testing"
+	"time"
+)
+
+// newTestRecipe returns a recipe of 200g of rice and 100g of beans
+func newTestRecipe() *models.Recipe {
+	rice := &models.Food{ID: "fdc_1", Name: "Rice", Calories: 130, Portions: []models.Portion{{Amount: 1, Description: "cup", GramWeight: 158}}}
+	beans := &models.Food{ID: "fdc_2", Name: "Black beans", Calories: 130}
+	return &models.Recipe{
+		Name:     "Rice and beans",
+		Servings: 2,
+		Ingredients: []models.FoodQuantity{
+			{Food: rice, Quantity: 200},
+			{Food: beans, Quantity: 100},
+		},
+	}
+}
+
+func TestRecipesCreateAndSearch(t *testing.T) {
+	s, _ := newTestDB(t)
+	r := s.Recipes()
+	recipe := newTestRecipe()
+	if err := r.CreateRecipe(recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.GetRecipe(recipe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != recipe.Name || got.Servings != 2 || len(got.Ingredients) != 2 {
+		t.Fatalf("recipe = %+v, want %+v", got, recipe)
+	}
+	rice := got.Ingredients[0]
+	if rice.Food.ID != "fdc_1" || rice.Quantity != 200 || len(rice.Food.Portions) != 1 {
+		t.Errorf("first ingredient = %+v, want 200g of rice with its portion", rice)
+	}
+
+	result, err := r.SearchFoods(models.FoodQuery{Text: "beans"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalHits != 1 || result.Foods[0].ID != recipe.ID || result.Foods[0].Calories != 130 {
+		t.Errorf("search = %+v, want the recipe at 130 kcal per 100g", result)
+	}
+
+	if _, err := r.GetFoodDetails("999"); !errors.Is(err, models.ErrFoodNotFound) {
+		t.Errorf("GetFoodDetails of an unknown recipe error = %v, want ErrFoodNotFound", err)
+	}
+}
+
+func TestRecipesUpdate(t *testing.T) {
+	s, userID := newTestDB(t)
+	r := s.Recipes()
+	recipe := newTestRecipe()
+	if err := r.CreateRecipe(recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server logs recipes under the ID prefixed with the source name
+	logged := recipe.ToFood()
+	logged.ID = r.Name() + "_" + recipe.ID
+	day := time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local)
+	logFood(t, s, userID, day, *logged)
+
+	// Adding 100g of butter makes the dish richer
+	butter := &models.Food{ID: "fdc_3", Name: "Butter", Calories: 717}
+	recipe.Ingredients = append(recipe.Ingredients, models.FoodQuantity{Food: butter, Quantity: 100})
+	if err := r.UpdateRecipe(recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.GetRecipe(recipe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Ingredients) != 3 {
+		t.Errorf("%d ingredients, want 3", len(got.Ingredients))
+	}
+
+	wantCalories := (130*2 + 130 + 717) / 4.0
+	stored, err := s.StoredFood(logged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Calories != wantCalories {
+		t.Errorf("stored recipe = %v kcal, want %v", stored.Calories, wantCalories)
+	}
+
+	// The log keeps the data the recipe was logged with
+	if past := loggedFood(t, s, userID, day); past.Calories != 130 {
+		t.Errorf("logged recipe = %v kcal, want 130", past.Calories)
+	}
+
+	recipe.ID = "999"
+	if err := r.UpdateRecipe(recipe); !errors.Is(err, models.ErrFoodNotFound) {
+		t.Errorf("UpdateRecipe of an unknown recipe error = %v, want ErrFoodNotFound", err)
+	}
+}
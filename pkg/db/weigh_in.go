@@ -0,0 +1,84 @@
+package db
+
+import (
+	"nutritionapp/pkg/models"
+	"time"
+)
+
+const weighInColumns = `date, weight, waist, hip, neck, body_fat`
+
+func scanWeighIn(row scanner) (*models.WeighIn, error) {
+	var w models.WeighIn
+	var dateStr string
+	if err := row.Scan(&dateStr, &w.Weight, &w.Waist, &w.Hip, &w.Neck, &w.BodyFat); err != nil {
+		return nil, err
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	w.Date = date
+	return &w, nil
+}
+
+// SaveWeighIn records a weigh-in, replacing any other one on the same day
+func (s *SQLiteDB) SaveWeighIn(userID int64, w *models.WeighIn) error {
+	_, err := s.db.Exec(`
+		INSERT INTO weigh_ins (user_id, date, weight, waist, hip, neck, body_fat)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, date) DO UPDATE SET
+			weight = excluded.weight,
+			waist = excluded.waist,
+			hip = excluded.hip,
+			neck = excluded.neck,
+			body_fat = excluded.body_fat
+	`, userID, w.Date.Format("2006-01-02"), w.Weight, w.Waist, w.Hip, w.Neck, w.BodyFat)
+	return err
+}
+
+// ListWeighIns retrieves a user's weigh-ins since a date, oldest first. A
+// zero date returns the whole history.
+func (s *SQLiteDB) ListWeighIns(userID int64, since time.Time) ([]models.WeighIn, error) {
+	sinceStr := ""
+	if !since.IsZero() {
+		sinceStr = since.Format("2006-01-02")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+weighInColumns+`
+		FROM weigh_ins
+		WHERE user_id = ? AND date >= ?
+		ORDER BY date
+	`, userID, sinceStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weighIns []models.WeighIn
+	for rows.Next() {
+		w, err := scanWeighIn(rows)
+		if err != nil {
+			return nil, err
+		}
+		weighIns = append(weighIns, *w)
+	}
+	return weighIns, rows.Err()
+}
+
+// GetLatestWeighIn retrieves a user's most recent weigh-in, or nil if there
+// is none
+func (s *SQLiteDB) GetLatestWeighIn(userID int64) *models.WeighIn {
+	w, err := scanWeighIn(s.db.QueryRow(`
+		SELECT `+weighInColumns+`
+		FROM weigh_ins
+		WHERE user_id = ?
+		ORDER BY date DESC
+		LIMIT 1
+	`, userID))
+	if err != nil {
+		return nil
+	}
+	return w
+}
@@ -0,0 +1,265 @@
+package fdc
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"nutritionapp/pkg/models"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BulkWriter receives the foods read from an FDC bulk download
+type BulkWriter interface {
+	AddFood(fdcID int64, dataType models.DataType, description string) error
+	SetBrand(fdcID int64, brandOwner string, servingSize float64, servingText, gtin string) error
+	AddNutrient(fdcID int64, n models.Nutrient) error
+	AddPortion(fdcID int64, position int, amount float64, description string, gramWeight float64) error
+}
+
+// BulkStats counts the rows imported from a bulk download
+type BulkStats struct {
+	Foods     int
+	Nutrients int
+	Portions  int
+}
+
+// bulkDataTypes maps the data types imported from the bulk download to
+// their API names. The others describe lab samples rather than foods.
+var bulkDataTypes = map[string]models.DataType{
+	"foundation_food":   models.DataTypeFoundation,
+	"sr_legacy_food":    models.DataTypeSRLegacy,
+	"survey_fndds_food": models.DataTypeSurvey,
+	"branded_food":      models.DataTypeBranded,
+}
+
+// ImportBulk reads the FoodData Central bulk CSV files (food.csv,
+// nutrient.csv, food_nutrient.csv, food_portion.csv and, for branded
+// products, branded_food.csv) from a directory or a zip archive, as
+// downloaded from https://fdc.nal.usda.gov/download-datasets
+func ImportBulk(source string, w BulkWriter) (BulkStats, error) {
+	var stats BulkStats
+
+	open, closeSource, err := openBulkSource(source)
+	if err != nil {
+		return stats, err
+	}
+	defer closeSource()
+
+	nutrients := make(map[string]models.Nutrient)
+	err = readCSV(open, "nutrient.csv", true, func(row csvRow) error {
+		number := strings.TrimSuffix(row.get("nutrient_nbr"), ".0")
+		if number == "" {
+			return nil
+		}
+		nutrients[row.get("id")] = models.Nutrient{
+			Number: number,
+			Name:   row.get("name"),
+			Unit:   unitName(row.get("unit_name")),
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	// measure_unit.csv is optional, portions are then described by their
+	// modifier alone
+	measureUnits := make(map[string]string)
+	err = readCSV(open, "measure_unit.csv", false, func(row csvRow) error {
+		measureUnits[row.get("id")] = row.get("name")
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	foods := make(map[int64]bool)
+	err = readCSV(open, "food.csv", true, func(row csvRow) error {
+		dataType, ok := bulkDataTypes[row.get("data_type")]
+		if !ok {
+			return nil
+		}
+		fdcID, err := strconv.ParseInt(row.get("fdc_id"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid fdc_id: %v", err)
+		}
+
+		foods[fdcID] = true
+		stats.Foods++
+		return w.AddFood(fdcID, dataType, row.get("description"))
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = readCSV(open, "branded_food.csv", false, func(row csvRow) error {
+		fdcID, err := strconv.ParseInt(row.get("fdc_id"), 10, 64)
+		if err != nil || !foods[fdcID] {
+			return nil
+		}
+
+		var servingSize float64
+		switch strings.ToLower(row.get("serving_size_unit")) {
+		case "g", "grm", "ml", "mlt":
+			servingSize, _ = strconv.ParseFloat(row.get("serving_size"), 64)
+		}
+		brandOwner := row.get("brand_owner")
+		if brandOwner == "" {
+			brandOwner = row.get("brand_name")
+		}
+		// Products with an invalid barcode can still be found by name
+		gtin, _ := models.NormalizeGTIN(row.get("gtin_upc"))
+		return w.SetBrand(fdcID, brandOwner, servingSize, row.get("household_serving_fulltext"), gtin)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = readCSV(open, "food_nutrient.csv", true, func(row csvRow) error {
+		fdcID, err := strconv.ParseInt(row.get("fdc_id"), 10, 64)
+		if err != nil || !foods[fdcID] {
+			return nil
+		}
+		n, ok := nutrients[row.get("nutrient_id")]
+		if !ok {
+			return nil
+		}
+		if n.Amount, err = strconv.ParseFloat(row.get("amount"), 64); err != nil {
+			return nil
+		}
+
+		stats.Nutrients++
+		return w.AddNutrient(fdcID, n)
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	// Portions are kept in file order: seq_num may be blank, or repeated
+	positions := make(map[int64]int)
+	err = readCSV(open, "food_portion.csv", false, func(row csvRow) error {
+		fdcID, err := strconv.ParseInt(row.get("fdc_id"), 10, 64)
+		if err != nil || !foods[fdcID] {
+			return nil
+		}
+		gramWeight, err := strconv.ParseFloat(row.get("gram_weight"), 64)
+		if err != nil || gramWeight <= 0 {
+			return nil
+		}
+		amount, err := strconv.ParseFloat(row.get("amount"), 64)
+		if err != nil || amount <= 0 {
+			amount = 1
+		}
+		description := portionDescription(row.get("portion_description"), measureUnits[row.get("measure_unit_id")], row.get("modifier"))
+		if description == "" {
+			return nil
+		}
+
+		position := positions[fdcID]
+		positions[fdcID]++
+		stats.Portions++
+		return w.AddPortion(fdcID, position, amount, description, gramWeight)
+	})
+	return stats, err
+}
+
+// bulkOpener opens a file of the bulk download by name
+type bulkOpener func(name string) (io.ReadCloser, error)
+
+// openBulkSource returns an opener for the CSV files of a directory or zip
+// archive. Files are found by name anywhere in a zip archive, as downloads
+// put them in a dated directory.
+func openBulkSource(source string) (bulkOpener, func() error, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		open := func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(source, name))
+		}
+		return open, func() error { return nil }, nil
+	}
+
+	archive, err := zip.OpenReader(source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither a directory nor a zip archive: %v", source, err)
+	}
+	open := func(name string) (io.ReadCloser, error) {
+		for _, f := range archive.File {
+			if path.Base(f.Name) == name {
+				return f.Open()
+			}
+		}
+		return nil, fs.ErrNotExist
+	}
+	return open, archive.Close, nil
+}
+
+// csvRow is a CSV record whose fields are accessed by column name
+type csvRow struct {
+	columns map[string]int
+	record  []string
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// readCSV calls handle for every row of a file of the bulk download. A
+// missing file is only an error when it is required.
+func readCSV(open bulkOpener, name string, required bool, handle func(row csvRow) error) error {
+	f, err := open(name)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	// Some releases start their files with a byte order mark
+	buffered := bufio.NewReader(f)
+	if r, _, err := buffered.ReadRune(); err == nil && r != '\ufeff' {
+		buffered.UnreadRune()
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	row := csvRow{columns: make(map[string]int)}
+	for i, column := range header {
+		row.columns[column] = i
+	}
+
+	for line := 2; ; line++ {
+		row.record, err = reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		if err := handle(row); err != nil {
+			return fmt.Errorf("%s line %d: %v", name, line, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package fdc
+
+import (
+	"fmt"
+	"nutritionapp/pkg/models"
+	"time"
+)
+
+// ErrorKind classifies why an FDC API call failed
+type ErrorKind int
+
+const (
+	ErrUnexpected ErrorKind = iota
+	ErrInvalidKey
+	ErrRateLimited
+	ErrNotFound
+	ErrServer
+)
+
+// APIError is an error status returned by the FDC API
+type APIError struct {
+	Kind       ErrorKind
+	StatusCode int
+	// RetryAfter is how long the API asked to wait, 0 if it did not say
+	RetryAfter time.Duration
+}
+
+// newAPIError classifies an error status
+func newAPIError(statusCode int, retryAfter time.Duration) *APIError {
+	err := &APIError{Kind: ErrUnexpected, StatusCode: statusCode, RetryAfter: retryAfter}
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		err.Kind = ErrInvalidKey
+	case statusCode == 429:
+		err.Kind = ErrRateLimited
+	case statusCode == 404:
+		err.Kind = ErrNotFound
+	case statusCode >= 500:
+		err.Kind = ErrServer
+	}
+	return err
+}
+
+func (e *APIError) Error() string {
+	switch e.Kind {
+	case ErrInvalidKey:
+		return "the FDC API rejected the API key, check FDC_API_KEY"
+	case ErrRateLimited:
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("the FDC API rate limit is reached, retry in %s", e.RetryAfter.Round(time.Second))
+		}
+		return "the FDC API rate limit is reached, retry later"
+	case ErrNotFound:
+		return "food not found in FDC"
+	case ErrServer:
+		return fmt.Sprintf("the FDC API is unavailable (status %d)", e.StatusCode)
+	default:
+		return fmt.Sprintf("unexpected FDC API response (status %d)", e.StatusCode)
+	}
+}
+
+// Is makes a not found error match models.ErrFoodNotFound
+func (e *APIError) Is(target error) bool {
+	return e.Kind == ErrNotFound && target == models.ErrFoodNotFound
+}
+
+// temporary reports whether the call may succeed if retried soon
+func (e *APIError) temporary(maxWait time.Duration) bool {
+	switch e.Kind {
+	case ErrServer:
+		return true
+	case ErrRateLimited:
+		return e.RetryAfter > 0 && e.RetryAfter <= maxWait
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package fdc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"nutritionapp/pkg/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       ErrorKind
+	}{
+		{400, ErrUnexpected},
+		{401, ErrInvalidKey},
+		{403, ErrInvalidKey},
+		{404, ErrNotFound},
+		{429, ErrRateLimited},
+		{500, ErrServer},
+		{503, ErrServer},
+	}
+
+	for _, tt := range tests {
+		if got := newAPIError(tt.statusCode, 0).Kind; got != tt.want {
+			t.Errorf("newAPIError(%d).Kind = %v, want %v", tt.statusCode, got, tt.want)
+		}
+	}
+
+	if !errors.Is(newAPIError(404, 0), models.ErrFoodNotFound) {
+		t.Error("a 404 error does not match models.ErrFoodNotFound")
+	}
+	if errors.Is(newAPIError(500, 0), models.ErrFoodNotFound) {
+		t.Error("a 500 error matches models.ErrFoodNotFound")
+	}
+}
+
+func TestAPIErrorTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want bool
+	}{
+		{"server error", newAPIError(503, 0), true},
+		{"short rate limit", newAPIError(429, 5*time.Second), true},
+		{"long rate limit", newAPIError(429, time.Hour), false},
+		{"rate limit without delay", newAPIError(429, 0), false},
+		{"invalid key", newAPIError(403, 0), false},
+		{"not found", newAPIError(404, 0), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.temporary(maxRetryWait); got != tt.want {
+			t.Errorf("%s: temporary() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	later := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if later <= 0 || later > time.Minute {
+		t.Errorf("parseRetryAfter(a date in a minute) = %v", later)
+	}
+}
+
+// failingAPI serves food details after failing with the given statuses, in
+// order, and counts the calls it received
+func failingAPI(t *testing.T, calls *int, statuses ...int) *httptest.Server {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *calls <= len(statuses) {
+			w.WriteHeader(statuses[*calls-1])
+			return
+		}
+		w.Write([]byte(`{"fdcId": 123, "description": "Milk, whole"}`))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		wantCalls  int
+		wantKind   ErrorKind // of the error, if any
+		wantErr    bool
+	}{
+		{"success", nil, 1, 1, 0, false},
+		{"server error retried", []int{503}, 1, 2, 0, false},
+		{"server error past retries", []int{503, 503}, 1, 2, ErrServer, true},
+		{"invalid key", []int{401}, 1, 1, ErrInvalidKey, true},
+		{"rate limit without delay", []int{429}, 1, 1, ErrRateLimited, true},
+		{"not found", []int{404}, 1, 1, ErrNotFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			api := failingAPI(t, &calls, tt.statuses...)
+			fp := NewFoodProcessor("key", WithBaseURL(api.URL), WithMaxRetries(tt.maxRetries))
+
+			food, err := fp.GetFoodDetails("123")
+			if calls != tt.wantCalls {
+				t.Errorf("%d calls, want %d", calls, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if err != nil || food.Name != "Milk, whole" {
+					t.Errorf("GetFoodDetails = %v, %v, want Milk, whole", food, err)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Kind != tt.wantKind {
+				t.Errorf("GetFoodDetails error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestGetUnreachableHidesKey(t *testing.T) {
+	api := httptest.NewServer(http.NotFoundHandler())
+	api.Close()
+	fp := NewFoodProcessor("secret-key", WithBaseURL(api.URL), WithMaxRetries(0))
+
+	_, err := fp.GetFoodDetails("123")
+	if !errors.Is(err, errUnreachable) {
+		t.Fatalf("GetFoodDetails error = %v, want an unreachable API", err)
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("error %q shows the API key", err)
+	}
+}
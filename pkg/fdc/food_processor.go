@@ -1,123 +1,421 @@
 package fdc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"nutritionapp/pkg/models"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Defaults of the FDC API client
+const (
+	DefaultBaseURL    = "https://api.nal.usda.gov/fdc/v1"
+	DefaultTimeout    = 15 * time.Second
+	DefaultMaxRetries = 3
+
+	// retryDelay is the wait before the first retry, doubled for each next one
+	retryDelay = 500 * time.Millisecond
+	// maxRetryWait is the longest wait before a retry, including a
+	// Retry-After asked by the API
+	maxRetryWait = 10 * time.Second
+)
+
+// errUnreachable wraps network errors, such as timeouts
+var errUnreachable = errors.New("failed to reach the FDC API")
+
 type FoodProcessor struct {
-	apiKey string
+	apiKey     string
+	baseURL    string
+	client     *http.Client
+	maxRetries int
 }
 
-func NewFoodProcessor(apiKey string) *FoodProcessor {
-	return &FoodProcessor{apiKey: apiKey}
+// Option configures a FoodProcessor
+type Option func(*FoodProcessor)
+
+// WithHTTPClient sets the HTTP client used to reach the API, which also
+// sets the request timeout
+func WithHTTPClient(client *http.Client) Option {
+	return func(fp *FoodProcessor) { fp.client = client }
 }
 
-func (fp *FoodProcessor) SearchFoods(query string) ([]models.Food, error) {
-	baseURL := "https://api.nal.usda.gov/fdc/v1/foods/search"
-	params := url.Values{}
-	params.Add("api_key", fp.apiKey)
-	params.Add("query", query)
-	params.Add("pageSize", "10")
-	params.Add("dataType", "SR Legacy")
+// WithBaseURL sets the API root, such as DefaultBaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(fp *FoodProcessor) { fp.baseURL = strings.TrimSuffix(baseURL, "/") }
+}
 
-	resp, err := http.Get(baseURL + "?" + params.Encode())
-	if err != nil {
-		return nil, fmt.Errorf("failed to search foods: %v", err)
+// WithMaxRetries sets how many times a call failing with a network error,
+// a server error or a short rate limit is retried
+func WithMaxRetries(maxRetries int) Option {
+	return func(fp *FoodProcessor) { fp.maxRetries = maxRetries }
+}
+
+func NewFoodProcessor(apiKey string, options ...Option) *FoodProcessor {
+	fp := &FoodProcessor{
+		apiKey:     apiKey,
+		baseURL:    DefaultBaseURL,
+		client:     &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+	}
+	for _, option := range options {
+		option(fp)
+	}
+	return fp
+}
+
+// Name identifies FDC as a food source
+func (fp *FoodProcessor) Name() string {
+	return "fdc"
+}
+
+func (fp *FoodProcessor) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	return fp.SearchFoodsContext(context.Background(), query)
+}
+
+// SearchFoodsContext searches a page of the foods of the query data types
+func (fp *FoodProcessor) SearchFoodsContext(ctx context.Context, query models.FoodQuery) (models.SearchResult, error) {
+	query = query.Normalize()
+	var dataTypes []string
+	for _, dataType := range query.EffectiveDataTypes() {
+		dataTypes = append(dataTypes, string(dataType))
+	}
+
+	params := url.Values{}
+	params.Add("query", query.Text)
+	params.Add("pageNumber", strconv.Itoa(query.Page))
+	params.Add("pageSize", strconv.Itoa(query.PageSize))
+	params.Add("dataType", strings.Join(dataTypes, ","))
+	if query.Sort == models.SortName {
+		params.Add("sortBy", "lowercaseDescription.keyword")
+		params.Add("sortOrder", "asc")
 	}
-	defer resp.Body.Close()
 
 	var result struct {
-		Foods []struct {
-			FdcID         int    `json:"fdcId"`
-			Description   string `json:"description"`
-			FoodNutrients []struct {
-				NutrientNumber string  `json:"nutrientNumber"`
-				Value          float64 `json:"value"`
-			} `json:"foodNutrients"`
-		} `json:"foods"`
+		TotalHits int       `json:"totalHits"`
+		Foods     []fdcFood `json:"foods"`
+	}
+	if err := fp.get(ctx, "/foods/search", params, &result); err != nil {
+		return models.SearchResult{}, fmt.Errorf("failed to search foods: %w", err)
+	}
+
+	found := models.SearchResult{TotalHits: result.TotalHits}
+	for _, f := range result.Foods {
+		found.Foods = append(found.Foods, *f.toFood())
+	}
+
+	return found, nil
+}
+
+func (fp *FoodProcessor) GetFoodDetails(fdcID string) (*models.Food, error) {
+	return fp.GetFoodDetailsContext(context.Background(), fdcID)
+}
+
+// GetFoodDetailsContext fetches a food by FDC ID
+func (fp *FoodProcessor) GetFoodDetailsContext(ctx context.Context, fdcID string) (*models.Food, error) {
+	if _, err := strconv.Atoi(fdcID); err != nil {
+		return nil, fmt.Errorf("%w: invalid FDC ID %q", models.ErrFoodNotFound, fdcID)
+	}
+
+	var result fdcFood
+	if err := fp.get(ctx, "/food/"+fdcID, url.Values{}, &result); err != nil {
+		return nil, fmt.Errorf("failed to get food details: %w", err)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	return result.toFood(), nil
+}
+
+func (fp *FoodProcessor) LookupBarcode(gtin string) (*models.Food, error) {
+	return fp.LookupBarcodeContext(context.Background(), gtin)
+}
+
+// LookupBarcodeContext finds the branded product with a barcode, normalized
+// by models.NormalizeGTIN. Products list their barcode with or without
+// leading zeros, so every length is searched.
+func (fp *FoodProcessor) LookupBarcodeContext(ctx context.Context, gtin string) (*models.Food, error) {
+	var codes []string
+	for _, length := range []int{8, 12, 13, 14} {
+		if code := gtin[len(gtin)-length:]; strings.Trim(gtin[:len(gtin)-length], "0") == "" {
+			codes = append(codes, code)
+		}
+	}
+
+	params := url.Values{}
+	params.Add("query", strings.Join(codes, " "))
+	params.Add("dataType", string(models.DataTypeBranded))
+	params.Add("pageSize", "50")
+
+	var result struct {
+		Foods []fdcFood `json:"foods"`
+	}
+	if err := fp.get(ctx, "/foods/search", params, &result); err != nil {
+		return nil, fmt.Errorf("failed to look up barcode: %w", err)
 	}
 
-	var foods []models.Food
 	for _, f := range result.Foods {
-		food := models.Food{
-			ID:   fmt.Sprintf("fdc_%d", f.FdcID),
-			Name: f.Description,
+		if food := f.toFood(); food.GTIN == gtin {
+			return food, nil
 		}
+	}
+	return nil, fmt.Errorf("%w: no product with barcode %s in FDC", models.ErrFoodNotFound, gtin)
+}
 
-		for _, n := range f.FoodNutrients {
-			switch n.NutrientNumber {
-			case "208":
-				food.Calories = n.Value
-			case "203":
-				food.Proteins = n.Value
-			case "205":
-				food.Carbs = n.Value
-			case "204":
-				food.Fats = n.Value
-			case "291":
-				food.Fiber = n.Value
-			}
+// get calls the API and decodes its JSON response into result, retrying
+// with exponential backoff when the failure may be temporary
+func (fp *FoodProcessor) get(ctx context.Context, path string, params url.Values, result any) error {
+	params.Set("api_key", fp.apiKey)
+	endpoint := fp.baseURL + path + "?" + params.Encode()
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		err := fp.getOnce(ctx, endpoint, result)
+		if err == nil || attempt >= fp.maxRetries || ctx.Err() != nil {
+			return err
 		}
 
-		foods = append(foods, food)
-	}
+		wait := delay
+		var apiErr *APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.temporary(maxRetryWait):
+			wait = max(wait, apiErr.RetryAfter)
+		case !errors.Is(err, errUnreachable):
+			return err
+		}
+		wait = min(wait, maxRetryWait)
 
-	return foods, nil
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
 }
 
-func (fp *FoodProcessor) GetFoodDetails(fdcID string) (*models.Food, error) {
-	baseURL := "https://api.nal.usda.gov/fdc/v1/food"
-	params := url.Values{}
-	params.Add("api_key", fp.apiKey)
+func (fp *FoodProcessor) getOnce(ctx context.Context, endpoint string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
 
-	// Extract numeric ID from string (e.g., "fdc_123" -> "123")
-	fdcNumericID := fdcID[4:]
-	resp, err := http.Get(fmt.Sprintf("%s/%s?%s", baseURL, fdcNumericID, params.Encode()))
+	resp, err := fp.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get food details: %v", err)
+		// The error would otherwise show the URL, API key included
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%w: %w", errUnreachable, err)
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		FdcID         int    `json:"fdcId"`
-		Description   string `json:"description"`
-		FoodNutrients []struct {
-			NutrientNumber string  `json:"nutrientNumber"`
-			Value          float64 `json:"value"`
-		} `json:"foodNutrients"`
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
 	}
+	return nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// parseRetryAfter reads a Retry-After header, either in seconds or a date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
 	}
+	return 0
+}
+
+// foodNutrient is a nutrient entry as returned by the API. Search results
+// use the flat fields while food details nest the nutrient description.
+type foodNutrient struct {
+	NutrientNumber string  `json:"nutrientNumber"`
+	NutrientName   string  `json:"nutrientName"`
+	UnitName       string  `json:"unitName"`
+	Value          float64 `json:"value"`
+
+	Nutrient *struct {
+		Number   string `json:"number"`
+		Name     string `json:"name"`
+		UnitName string `json:"unitName"`
+	} `json:"nutrient"`
+	Amount float64 `json:"amount"`
+}
+
+// toNutrient converts the entry, whichever layout it uses
+func (n foodNutrient) toNutrient() models.Nutrient {
+	if n.Nutrient != nil {
+		return models.Nutrient{
+			Number: n.Nutrient.Number,
+			Name:   n.Nutrient.Name,
+			Unit:   unitName(n.Nutrient.UnitName),
+			Amount: n.Amount,
+		}
+	}
+	return models.Nutrient{
+		Number: n.NutrientNumber,
+		Name:   n.NutrientName,
+		Unit:   unitName(n.UnitName),
+		Amount: n.Value,
+	}
+}
+
+// unitName normalizes an FDC unit name such as "MG" or "UG"
+func unitName(unit string) string {
+	unit = strings.ToLower(unit)
+	if unit == "ug" {
+		return "µg"
+	}
+	return unit
+}
+
+// fdcFood is a food as returned by the API, in search results or details.
+// The fields present depend on the data type.
+type fdcFood struct {
+	FdcID         int            `json:"fdcId"`
+	Description   string         `json:"description"`
+	DataType      string         `json:"dataType"`
+	FoodNutrients []foodNutrient `json:"foodNutrients"`
+
+	// Branded products only
+	BrandOwner               string  `json:"brandOwner"`
+	BrandName                string  `json:"brandName"`
+	ServingSize              float64 `json:"servingSize"`
+	ServingSizeUnit          string  `json:"servingSizeUnit"`
+	HouseholdServingFullText string  `json:"householdServingFullText"`
+	GtinUpc                  string  `json:"gtinUpc"`
+	// LabelNutrients are the nutrition facts per serving, only in details
+	LabelNutrients map[string]struct {
+		Value float64 `json:"value"`
+	} `json:"labelNutrients"`
+
+	// Household measures, as foodPortions in details and foodMeasures in
+	// search results
+	FoodPortions []fdcPortion `json:"foodPortions"`
+	FoodMeasures []fdcMeasure `json:"foodMeasures"`
+}
 
+type fdcPortion struct {
+	Amount             float64 `json:"amount"`
+	GramWeight         float64 `json:"gramWeight"`
+	Modifier           string  `json:"modifier"`
+	PortionDescription string  `json:"portionDescription"`
+	MeasureUnit        struct {
+		Name string `json:"name"`
+	} `json:"measureUnit"`
+}
+
+type fdcMeasure struct {
+	DisseminationText string  `json:"disseminationText"`
+	GramWeight        float64 `json:"gramWeight"`
+}
+
+// portionDescription describes a portion by its description, or by its unit
+// and modifier, as in "cup, chopped", when it has none
+func portionDescription(description, unit, modifier string) string {
+	if description != "" {
+		return description
+	}
+	if unit == "undetermined" {
+		unit = ""
+	}
+	return strings.TrimSpace(unit + " " + modifier)
+}
+
+// toPortions converts the household measures of the food, leaving them nil
+// when the response has none, as search results often do
+func (f fdcFood) toPortions() []models.Portion {
+	var portions []models.Portion
+	for _, p := range f.FoodPortions {
+		description := portionDescription(p.PortionDescription, p.MeasureUnit.Name, p.Modifier)
+		if p.GramWeight <= 0 || description == "" {
+			continue
+		}
+		amount := p.Amount
+		if amount <= 0 {
+			amount = 1
+		}
+		portions = append(portions, models.Portion{Amount: amount, Description: description, GramWeight: p.GramWeight})
+	}
+	for _, m := range f.FoodMeasures {
+		if m.GramWeight <= 0 || m.DisseminationText == "" {
+			continue
+		}
+		portions = append(portions, models.Portion{Amount: 1, Description: m.DisseminationText, GramWeight: m.GramWeight})
+	}
+	return portions
+}
+
+// labelNutrients maps the nutrition facts of branded products to nutrients
+var labelNutrients = map[string]models.Nutrient{
+	"calories":      {Number: models.NutrientEnergy, Name: "Energy", Unit: "kcal"},
+	"protein":       {Number: models.NutrientProtein, Name: "Protein", Unit: "g"},
+	"fat":           {Number: models.NutrientFat, Name: "Total lipid (fat)", Unit: "g"},
+	"carbohydrates": {Number: models.NutrientCarbs, Name: "Carbohydrate, by difference", Unit: "g"},
+	"fiber":         {Number: models.NutrientFiber, Name: "Fiber, total dietary", Unit: "g"},
+	"sugars":        {Number: "269", Name: "Sugars, total", Unit: "g"},
+	"saturatedFat":  {Number: "606", Name: "Fatty acids, total saturated", Unit: "g"},
+	"transFat":      {Number: "605", Name: "Fatty acids, total trans", Unit: "g"},
+	"cholesterol":   {Number: "601", Name: "Cholesterol", Unit: "mg"},
+	"sodium":        {Number: "307", Name: "Sodium, Na", Unit: "mg"},
+	"potassium":     {Number: "306", Name: "Potassium, K", Unit: "mg"},
+	"calcium":       {Number: "301", Name: "Calcium, Ca", Unit: "mg"},
+	"iron":          {Number: "303", Name: "Iron, Fe", Unit: "mg"},
+}
+
+// toFood converts the food, with all nutrients given per 100g. Nutrients
+// only known from the label of a branded product are scaled from its
+// serving.
+func (f fdcFood) toFood() *models.Food {
 	food := &models.Food{
-		ID:   fmt.Sprintf("fdc_%d", result.FdcID),
-		Name: result.Description,
-	}
-
-	for _, n := range result.FoodNutrients {
-		switch n.NutrientNumber {
-		case "208":
-			food.Calories = n.Value
-		case "203":
-			food.Proteins = n.Value
-		case "205":
-			food.Carbs = n.Value
-		case "204":
-			food.Fats = n.Value
-		case "291":
-			food.Fiber = n.Value
+		ID:          strconv.Itoa(f.FdcID),
+		Name:        f.Description,
+		Nutrients:   map[string]models.Nutrient{},
+		DataType:    models.DataType(f.DataType),
+		BrandOwner:  f.BrandOwner,
+		ServingText: f.HouseholdServingFullText,
+		Portions:    f.toPortions(),
+	}
+	// Some products have invalid barcodes, which no lookup could match
+	food.GTIN, _ = models.NormalizeGTIN(f.GtinUpc)
+	if food.BrandOwner == "" {
+		food.BrandOwner = f.BrandName
+	}
+	switch strings.ToLower(f.ServingSizeUnit) {
+	case "g", "grm", "ml", "mlt":
+		food.ServingSize = f.ServingSize
+	}
+
+	for _, entry := range f.FoodNutrients {
+		if n := entry.toNutrient(); n.Number != "" {
+			food.SetNutrient(n)
+		}
+	}
+
+	food.FillEnergy()
+
+	if food.ServingSize > 0 {
+		for key, label := range f.LabelNutrients {
+			n, ok := labelNutrients[key]
+			if _, known := food.Nutrients[n.Number]; !ok || known {
+				continue
+			}
+			n.Amount = label.Value * 100 / food.ServingSize
+			food.SetNutrient(n)
 		}
 	}
 
-	return food, nil
+	return food
 }
@@ -0,0 +1,158 @@
+package fdc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"nutritionapp/pkg/models"
+	"testing"
+)
+
+// decodeFood converts an API food given as JSON
+func decodeFood(t *testing.T, data string) *models.Food {
+	t.Helper()
+	var f fdcFood
+	if err := json.Unmarshal([]byte(data), &f); err != nil {
+		t.Fatal(err)
+	}
+	return f.toFood()
+}
+
+func TestToFoodBranded(t *testing.T) {
+	food := decodeFood(t, `{
+		"fdcId": 2000,
+		"description": "Granola bar",
+		"dataType": "Branded",
+		"brandName": "Crunchy",
+		"servingSize": 40,
+		"servingSizeUnit": "GRM",
+		"householdServingFullText": "1 bar",
+		"gtinUpc": "012345678905",
+		"foodNutrients": [
+			{"nutrient": {"number": "208", "name": "Energy", "unitName": "KCAL"}, "amount": 450},
+			{"nutrient": {"number": "401", "name": "Vitamin C", "unitName": "UG"}, "amount": 12}
+		],
+		"labelNutrients": {
+			"calories": {"value": 200},
+			"protein": {"value": 6},
+			"sodium": {"value": 80},
+			"unknown": {"value": 1}
+		}
+	}`)
+
+	if food.ID != "2000" || food.DataType != models.DataTypeBranded || food.BrandOwner != "Crunchy" {
+		t.Errorf("food = %s %q of %q, want 2000 Branded of Crunchy", food.ID, food.DataType, food.BrandOwner)
+	}
+	if food.ServingSize != 40 || food.ServingText != "1 bar" || food.GTIN != "00012345678905" {
+		t.Errorf("serving %v %q, barcode %q, want 40 g per 1 bar, 00012345678905", food.ServingSize, food.ServingText, food.GTIN)
+	}
+
+	tests := []struct {
+		number string
+		want   float64
+		unit   string
+	}{
+		// The per 100g nutrients win over the label
+		{models.NutrientEnergy, 450, "kcal"},
+		{"401", 12, "µg"},
+		// Label nutrients are given per serving of 40g
+		{models.NutrientProtein, 15, "g"},
+		{"307", 200, "mg"},
+	}
+	for _, tt := range tests {
+		n, ok := food.Nutrients[tt.number]
+		if !ok || n.Amount != tt.want || n.Unit != tt.unit {
+			t.Errorf("nutrient %s = %+v, want %v %s", tt.number, n, tt.want, tt.unit)
+		}
+	}
+	if len(food.Nutrients) != 4 {
+		t.Errorf("%d nutrients, want 4", len(food.Nutrients))
+	}
+	if food.Calories != 450 || food.Proteins != 15 {
+		t.Errorf("macros = %v kcal, %v g protein, want 450 kcal, 15 g", food.Calories, food.Proteins)
+	}
+}
+
+func TestToFoodLabelWithoutServing(t *testing.T) {
+	food := decodeFood(t, `{
+		"fdcId": 2001,
+		"dataType": "Branded",
+		"servingSize": 1,
+		"servingSizeUnit": "ONZ",
+		"labelNutrients": {"calories": {"value": 100}}
+	}`)
+
+	// Servings in other units than grams or milliliters cannot be scaled
+	if food.ServingSize != 0 || len(food.Nutrients) != 0 {
+		t.Errorf("serving %v, nutrients %v, want neither", food.ServingSize, food.Nutrients)
+	}
+}
+
+func TestToFoodPortions(t *testing.T) {
+	food := decodeFood(t, `{
+		"fdcId": 170000,
+		"dataType": "Foundation",
+		"foodNutrients": [{"nutrientNumber": "958", "nutrientName": "Energy (Atwater)", "unitName": "KCAL", "value": 52}],
+		"foodPortions": [
+			{"amount": 1, "gramWeight": 182, "portionDescription": "1 medium"},
+			{"amount": 0, "gramWeight": 125, "modifier": "chopped", "measureUnit": {"name": "cup"}},
+			{"amount": 1, "gramWeight": 5, "modifier": "slice", "measureUnit": {"name": "undetermined"}},
+			{"amount": 1, "gramWeight": 0, "portionDescription": "weightless"}
+		],
+		"foodMeasures": [{"disseminationText": "1 large", "gramWeight": 223}]
+	}`)
+
+	want := []models.Portion{
+		{Amount: 1, Description: "1 medium", GramWeight: 182},
+		{Amount: 1, Description: "cup chopped", GramWeight: 125},
+		{Amount: 1, Description: "slice", GramWeight: 5},
+		{Amount: 1, Description: "1 large", GramWeight: 223},
+	}
+	if len(food.Portions) != len(want) {
+		t.Fatalf("portions = %v, want %v", food.Portions, want)
+	}
+	for i := range want {
+		if food.Portions[i] != want[i] {
+			t.Errorf("portion %d = %+v, want %+v", i, food.Portions[i], want[i])
+		}
+	}
+	if food.Calories != 52 {
+		t.Errorf("calories = %v, want the Atwater energy of 52", food.Calories)
+	}
+}
+
+func TestSearchFoodsParams(t *testing.T) {
+	var params map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = map[string]string{}
+		for key := range r.URL.Query() {
+			params[key] = r.URL.Query().Get(key)
+		}
+		w.Write([]byte(`{"totalHits": 1, "foods": [{"fdcId": 1, "description": "Milk"}]}`))
+	}))
+	defer api.Close()
+	fp := NewFoodProcessor("key", WithBaseURL(api.URL))
+
+	result, err := fp.SearchFoods(models.FoodQuery{Text: "milk", Page: 2, Sort: models.SortName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalHits != 1 || len(result.Foods) != 1 || result.Foods[0].Name != "Milk" {
+		t.Errorf("result = %+v, want Milk", result)
+	}
+
+	want := map[string]string{
+		"query":      "milk",
+		"pageNumber": "2",
+		"pageSize":   "10",
+		"dataType":   "Foundation,SR Legacy,Survey (FNDDS)",
+		"sortBy":     "lowercaseDescription.keyword",
+		"sortOrder":  "asc",
+		"api_key":    "key",
+	}
+	for key, value := range want {
+		if params[key] != value {
+			t.Errorf("param %s = %q, want %q", key, params[key], value)
+		}
+	}
+}
@@ -4,30 +4,31 @@ import "time"
 
 // DailyLog represents a user's daily food log
 type DailyLog struct {
-	Date  time.Time
-	Meals []*Meal
+	UserID int64
+	Date   time.Time
+	Meals  []*Meal
 }
 
-// NutritionTotals represents the total nutritional values
-type NutritionTotals struct {
-	Calories float64
-	Proteins float64
-	Carbs    float64
-	Fats     float64
-	Fiber    float64
+// RemoveMeal removes the meal at the given index
+func (dl *DailyLog) RemoveMeal(index int) {
+	dl.Meals = append(dl.Meals[:index], dl.Meals[index+1:]...)
 }
 
 // CalculateTotals calculates the total nutritional values for the day
-func (dl *DailyLog) CalculateTotals() *NutritionTotals {
-	totals := &NutritionTotals{}
+func (dl *DailyLog) CalculateTotals() NutritionalTotals {
+	var totals NutritionalTotals
 	for _, meal := range dl.Meals {
-		for _, food := range meal.Foods {
-			totals.Calories += food.Food.Calories * food.Quantity
-			totals.Proteins += food.Food.Proteins * food.Quantity
-			totals.Carbs += food.Food.Carbs * food.Quantity
-			totals.Fats += food.Food.Fats * food.Quantity
-			totals.Fiber += food.Food.Fiber * food.Quantity
-		}
+		totals.Add(meal.CalculateTotals())
 	}
 	return totals
 }
+
+// HasFood reports whether any food was logged on that day
+func (dl *DailyLog) HasFood() bool {
+	for _, meal := range dl.Meals {
+		if len(meal.Foods) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CommonMealNames are the usual meal names, recognized at the end of a
+// quick-add line even when the day has no such meal yet
+var CommonMealNames = []string{"breakfast", "lunch", "dinner", "snack"}
+
+// FoodLine is a quick-add line, such as "150g chicken breast to lunch" or
+// "2 eggs, 1 slice toast breakfast"
+type FoodLine struct {
+	Entries []FoodEntry
+	// Meal names the meal to add the foods to, empty when the line has none
+	Meal string
+}
+
+// FoodEntry is a food of a quick-add line with its quantity
+type FoodEntry struct {
+	Text   string  // as typed, such as "2 slices toast"
+	Amount float64 // of Unit, or of the food itself when Unit is empty
+	Unit   string  // such as "g" or "slices", empty as in "2 eggs"
+	Food   string  // the words naming the food
+}
+
+// ParseFoodLine parses a quick-add line, whose foods are separated by commas.
+// The line may end with its meal, either one of meals or CommonMealNames,
+// which "to", "for" or "in" may precede. Other trailing words belong to the
+// last food, as in "cereal for kids".
+func ParseFoodLine(line string, meals []string) (FoodLine, error) {
+	var result FoodLine
+	words, meal := splitMeal(strings.Fields(line), meals)
+	result.Meal = meal
+
+	for _, text := range strings.Split(strings.Join(words, " "), ",") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		entry, err := ParseFoodEntry(text)
+		if err != nil {
+			return result, err
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	if len(result.Entries) == 0 {
+		return result, fmt.Errorf("missing food, as in \"150g chicken breast to lunch\"")
+	}
+	return result, nil
+}
+
+// splitMeal splits the words of a quick-add line into those of its foods and
+// the name of its meal, empty when the line does not end with a known meal.
+// The longest known name wins, as "late snack" over "snack".
+func splitMeal(words []string, meals []string) ([]string, string) {
+	var meal string
+	size := 0
+	for _, name := range append(slices.Clone(meals), CommonMealNames...) {
+		nameWords := strings.Fields(name)
+		n := len(nameWords)
+		if n == 0 || n <= size || n >= len(words) {
+			continue
+		}
+		if slices.EqualFunc(words[len(words)-n:], nameWords, strings.EqualFold) {
+			meal, size = name, n
+		}
+	}
+	if size == 0 {
+		return words, ""
+	}
+
+	words = words[:len(words)-size]
+	if last := strings.ToLower(words[len(words)-1]); last == "to" || last == "for" || last == "in" {
+		words = words[:len(words)-1]
+	}
+	return words, meal
+}
+
+// ParseFoodEntry parses a food of a quick-add line, such as "150g chicken
+// breast", "8 oz of salmon", "1 1/2 cups rice", "2 eggs" or "an apple". Only
+// the weight units are told apart from the food: whether "1 slice toast" is a
+// slice of toast depends on the food, see HouseholdMeasure.
+func ParseFoodEntry(text string) (FoodEntry, error) {
+	entry := FoodEntry{Text: strings.TrimSpace(text), Amount: 1}
+	words := strings.Fields(entry.Text)
+	if len(words) == 0 {
+		return entry, fmt.Errorf("missing food")
+	}
+
+	first := strings.ToLower(words[0])
+	switch {
+	case first == "a" || first == "an":
+		words = words[1:]
+	case strings.IndexFunc(first, unicode.IsDigit) == 0:
+		quantity, n := first, 1
+		// A mixed number, as in "1 1/2 cups flour"
+		if len(words) > 2 && isWholeNumber(first) && strings.IndexFunc(words[1], unicode.IsDigit) == 0 && strings.Contains(words[1], "/") {
+			quantity, n = first+" "+words[1], 2
+		}
+		amount, unit, err := splitQuantity(quantity)
+		if err != nil {
+			return entry, fmt.Errorf("invalid quantity in %q: %v", entry.Text, err)
+		}
+		if amount <= 0 {
+			return entry, fmt.Errorf("quantity must be positive in %q", entry.Text)
+		}
+		entry.Amount, entry.Unit = amount, unit
+		words = words[n:]
+		if entry.Unit == "" && len(words) > 1 && isWeightUnit(words[0]) {
+			entry.Unit, words = words[0], words[1:]
+		}
+	}
+
+	entry.Food = foodName(words)
+	if entry.Food == "" {
+		return entry, fmt.Errorf("missing food in %q", entry.Text)
+	}
+	return entry, nil
+}
+
+// HouseholdMeasure reads the first word of the food as the unit of the
+// amount, as in "1 slice toast" or "2 large eggs", when the entry has none
+func (e FoodEntry) HouseholdMeasure() (FoodEntry, bool) {
+	words := strings.Fields(e.Food)
+	if e.Unit != "" || len(words) < 2 {
+		return e, false
+	}
+	e.Unit = words[0]
+	e.Food = foodName(words[1:])
+	return e, e.Food != ""
+}
+
+// SearchTexts returns the texts to search the food with: the food as typed
+// then, when it differs, in the singular, as food names are, so that "eggs"
+// also finds "Egg, whole"
+func (e FoodEntry) SearchTexts() []string {
+	words := strings.Fields(strings.ToLower(e.Food))
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	texts := []string{e.Food}
+	if text := strings.Join(words, " "); text != strings.ToLower(e.Food) {
+		texts = append(texts, text)
+	}
+	return texts
+}
+
+// Grams converts the quantity of the entry to grams of a food. Without a
+// unit, the amount counts servings of the food or, when it has no label
+// serving, its first portion, as large eggs for "2 eggs".
+func (e FoodEntry) Grams(f *Food) (float64, error) {
+	if e.Unit != "" {
+		return f.ParseQuantity(strconv.FormatFloat(e.Amount, 'f', -1, 64) + " " + e.Unit)
+	}
+	if f.ServingSize > 0 {
+		return e.Amount * f.ServingSize, nil
+	}
+	for _, portion := range f.Portions {
+		if portion.GramWeight > 0 {
+			amount, _ := portion.Measure()
+			return e.Amount / amount * portion.GramWeight, nil
+		}
+	}
+	return 0, fmt.Errorf("no portion is known for %s, give a weight such as 100g", f.Name)
+}
+
+// foodName joins the words naming a food, without a leading "of"
+func foodName(words []string) string {
+	if len(words) > 1 && strings.EqualFold(words[0], "of") {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func isWeightUnit(word string) bool {
+	_, ok := weightUnits[strings.Join(unitWords(word), " ")]
+	return ok
+}
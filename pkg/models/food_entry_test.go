@@ -0,0 +1,164 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFoodLine(t *testing.T) {
+	meals := []string{"Breakfast", "Late snack", "Post workout"}
+	tests := []struct {
+		line     string
+		wantMeal string
+		wantFood []string
+		wantErr  bool
+	}{
+		{"150g chicken breast to lunch", "lunch", []string{"chicken breast"}, false},
+		{"2 eggs, 1 slice toast breakfast", "Breakfast", []string{"eggs", "slice toast"}, false},
+		{"2 eggs, 1 slice toast for BREAKFAST", "Breakfast", []string{"eggs", "slice toast"}, false},
+		{"yogurt in late snack", "Late snack", []string{"yogurt"}, false},
+		{"yogurt snack", "snack", []string{"yogurt"}, false},
+		{"shake to post workout", "Post workout", []string{"shake"}, false},
+		{"cereal for kids", "", []string{"cereal for kids"}, false},
+		{"rice to go", "", []string{"rice to go"}, false},
+		{"an apple", "", []string{"apple"}, false},
+		{"1 1/2 cups rice, 8 oz of salmon dinner", "dinner", []string{"cups rice", "salmon"}, false},
+		{"2 eggs,, toast", "", []string{"eggs", "toast"}, false},
+		{"lunch", "", []string{"lunch"}, false},
+		{"to lunch", "", nil, true},
+		{"", "", nil, true},
+		{"0 eggs", "", nil, true},
+		{"150g", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		line, err := ParseFoodLine(tt.line, meals)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFoodLine(%q) error = %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		var foods []string
+		for _, entry := range line.Entries {
+			foods = append(foods, entry.Food)
+		}
+		if line.Meal != tt.wantMeal || !reflect.DeepEqual(foods, tt.wantFood) {
+			t.Errorf("ParseFoodLine(%q) = meal %q, foods %q, want meal %q, foods %q", tt.line, line.Meal, foods, tt.wantMeal, tt.wantFood)
+		}
+	}
+}
+
+func TestParseFoodEntry(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    FoodEntry
+		wantErr bool
+	}{
+		{"150g chicken breast", FoodEntry{Amount: 150, Unit: "g", Food: "chicken breast"}, false},
+		{"150 g chicken breast", FoodEntry{Amount: 150, Unit: "g", Food: "chicken breast"}, false},
+		{"8 oz of salmon", FoodEntry{Amount: 8, Unit: "oz", Food: "salmon"}, false},
+		{"2 eggs", FoodEntry{Amount: 2, Food: "eggs"}, false},
+		{"an apple", FoodEntry{Amount: 1, Food: "apple"}, false},
+		{"banana", FoodEntry{Amount: 1, Food: "banana"}, false},
+		{"1 slice toast", FoodEntry{Amount: 1, Food: "slice toast"}, false},
+		{"1/2 cup oats", FoodEntry{Amount: 0.5, Food: "cup oats"}, false},
+		{"1 1/2 cups rice", FoodEntry{Amount: 1.5, Food: "cups rice"}, false},
+		{"1 1/2cups rice", FoodEntry{Amount: 1.5, Unit: "cups", Food: "rice"}, false},
+		{"2 g", FoodEntry{Amount: 2, Food: "g"}, false},
+		{"of", FoodEntry{Amount: 1, Food: "of"}, false},
+		{"0 eggs", FoodEntry{}, true},
+		{"1/0 cup", FoodEntry{}, true},
+		{"a", FoodEntry{}, true},
+		{"  ", FoodEntry{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFoodEntry(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFoodEntry(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		got.Text = ""
+		if got != tt.want {
+			t.Errorf("ParseFoodEntry(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFoodEntryHouseholdMeasure(t *testing.T) {
+	tests := []struct {
+		entry  FoodEntry
+		want   FoodEntry
+		wantOK bool
+	}{
+		{FoodEntry{Amount: 1, Food: "slice toast"}, FoodEntry{Amount: 1, Unit: "slice", Food: "toast"}, true},
+		{FoodEntry{Amount: 2, Food: "large eggs"}, FoodEntry{Amount: 2, Unit: "large", Food: "eggs"}, true},
+		{FoodEntry{Amount: 1, Food: "cup of milk"}, FoodEntry{Amount: 1, Unit: "cup", Food: "milk"}, true},
+		{FoodEntry{Amount: 2, Food: "eggs"}, FoodEntry{Amount: 2, Food: "eggs"}, false},
+		{FoodEntry{Amount: 150, Unit: "g", Food: "chicken breast"}, FoodEntry{Amount: 150, Unit: "g", Food: "chicken breast"}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.entry.HouseholdMeasure()
+		if ok != tt.wantOK || ok && got != tt.want {
+			t.Errorf("%+v.HouseholdMeasure() = %+v, %v, want %+v, %v", tt.entry, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFoodEntrySearchTexts(t *testing.T) {
+	tests := []struct {
+		food string
+		want []string
+	}{
+		{"eggs", []string{"eggs", "egg"}},
+		{"cherry tomatoes", []string{"cherry tomatoes", "cherry tomato"}},
+		{"Blueberries", []string{"Blueberries", "blueberry"}},
+		{"rice", []string{"rice"}},
+		{"Rice", []string{"Rice"}},
+	}
+
+	for _, tt := range tests {
+		got := FoodEntry{Food: tt.food}.SearchTexts()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTexts(%q) = %q, want %q", tt.food, got, tt.want)
+		}
+	}
+}
+
+func TestFoodEntryGrams(t *testing.T) {
+	egg := &Food{Name: "Egg", Portions: []Portion{{Amount: 1, Description: "large", GramWeight: 50}}}
+	bar := &Food{Name: "Granola bar", ServingSize: 40}
+	salt := &Food{Name: "Salt"}
+
+	tests := []struct {
+		entry   FoodEntry
+		food    *Food
+		want    float64
+		wantErr bool
+	}{
+		{FoodEntry{Amount: 2, Food: "eggs"}, egg, 100, false},
+		{FoodEntry{Amount: 2, Unit: "large", Food: "eggs"}, egg, 100, false},
+		{FoodEntry{Amount: 150, Unit: "g", Food: "egg"}, egg, 150, false},
+		{FoodEntry{Amount: 1.5, Food: "bars"}, bar, 60, false},
+		{FoodEntry{Amount: 1, Food: "salt"}, salt, 0, true},
+		{FoodEntry{Amount: 1, Unit: "slice", Food: "egg"}, egg, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.entry.Grams(tt.food)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Grams(%s) error = %v, want error %v", tt.entry, tt.food.Name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%+v.Grams(%s) = %v, want %v", tt.entry, tt.food.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+// DataType is an FDC data type, named as in the FDC API
+type DataType string
+
+const (
+	DataTypeFoundation DataType = "Foundation"
+	DataTypeSRLegacy   DataType = "SR Legacy"
+	DataTypeSurvey     DataType = "Survey (FNDDS)"
+	DataTypeBranded    DataType = "Branded"
+	// DataTypeCustom marks the foods created by users. It and DataTypeRecipe
+	// are not FDC data types, and cannot be searched for.
+	DataTypeCustom DataType = "Custom"
+	// DataTypeRecipe marks the dishes made of other foods
+	DataTypeRecipe DataType = "Recipe"
+)
+
+// DataTypes lists the valid data types
+var DataTypes = []DataType{DataTypeFoundation, DataTypeSRLegacy, DataTypeSurvey, DataTypeBranded}
+
+// DefaultDataTypes are searched when a query does not select any: generic
+// foods rather than branded products
+var DefaultDataTypes = []DataType{DataTypeFoundation, DataTypeSRLegacy, DataTypeSurvey}
+
+// IsValid reports whether the data type is known
+func (t DataType) IsValid() bool {
+	for _, dataType := range DataTypes {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders of food searches
+const (
+	SortRelevance = "relevance"
+	SortName      = "name"
+)
+
+const (
+	DefaultPageSize = 10
+	// MaxPageSize is the largest page the FDC API returns
+	MaxPageSize = 200
+	// MaxResults bounds how deep searches go: pages must end within the
+	// first MaxResults foods, as every source is read up to the page end
+	MaxResults = 1000
+)
+
+// FoodQuery is a food search
+type FoodQuery struct {
+	Text string
+	// DataTypes restricts the search, DefaultDataTypes when empty
+	DataTypes []DataType
+
+	// Page starts at 1. Zero values mean the first page of DefaultPageSize
+	// foods, by relevance.
+	Page     int
+	PageSize int
+	Sort     string
+}
+
+// SearchResult is a page of foods found by a search
+type SearchResult struct {
+	Foods []Food
+	// TotalHits is the number of foods found over all pages
+	TotalHits int
+}
+
+// Normalize applies the defaults of the query paging
+func (q FoodQuery) Normalize() FoodQuery {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = DefaultPageSize
+	}
+	q.PageSize = min(q.PageSize, MaxPageSize)
+	if q.Sort == "" {
+		q.Sort = SortRelevance
+	}
+	return q
+}
+
+// EffectiveDataTypes returns the searched data types, applying the default
+// when none is selected
+func (q FoodQuery) EffectiveDataTypes() []DataType {
+	if len(q.DataTypes) == 0 {
+		return DefaultDataTypes
+	}
+	return q.DataTypes
+}
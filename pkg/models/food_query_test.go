@@ -0,0 +1,64 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFoodQueryNormalize(t *testing.T) {
+	tests := []struct {
+		query FoodQuery
+		want  FoodQuery
+	}{
+		{FoodQuery{}, FoodQuery{Page: 1, PageSize: DefaultPageSize, Sort: SortRelevance}},
+		{FoodQuery{Page: -2, PageSize: -1}, FoodQuery{Page: 1, PageSize: DefaultPageSize, Sort: SortRelevance}},
+		{FoodQuery{Page: 3, PageSize: 25, Sort: SortName}, FoodQuery{Page: 3, PageSize: 25, Sort: SortName}},
+		{FoodQuery{PageSize: 1000}, FoodQuery{Page: 1, PageSize: MaxPageSize, Sort: SortRelevance}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.query.Normalize(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.Normalize() = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFoodQueryEffectiveDataTypes(t *testing.T) {
+	tests := []struct {
+		dataTypes []DataType
+		want      []DataType
+	}{
+		{nil, DefaultDataTypes},
+		{[]DataType{}, DefaultDataTypes},
+		{[]DataType{DataTypeBranded}, []DataType{DataTypeBranded}},
+	}
+
+	for _, tt := range tests {
+		got := FoodQuery{DataTypes: tt.dataTypes}.EffectiveDataTypes()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EffectiveDataTypes(%q) = %q, want %q", tt.dataTypes, got, tt.want)
+		}
+	}
+}
+
+func TestDataTypeIsValid(t *testing.T) {
+	tests := []struct {
+		dataType DataType
+		want     bool
+	}{
+		{DataTypeFoundation, true},
+		{DataTypeSRLegacy, true},
+		{DataTypeSurvey, true},
+		{DataTypeBranded, true},
+		{DataTypeCustom, false},
+		{DataTypeRecipe, false},
+		{"branded", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.dataType.IsValid(); got != tt.want {
+			t.Errorf("DataType(%q).IsValid() = %v, want %v", tt.dataType, got, tt.want)
+		}
+	}
+}
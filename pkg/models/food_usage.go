@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// FoodUsage describes how often and how recently a user logged a food
+type FoodUsage struct {
+	Food     Food
+	Uses     int       // number of meal items logging the food
+	LastUsed time.Time // time of the latest meal logging it, zero if none
+	Starred  bool      // whether the user marked the food as a favorite
+}
@@ -0,0 +1,119 @@
+package models
+
+import "math"
+
+// GoalType is what a user wants to achieve with their diet
+type GoalType string
+
+const (
+	GoalWeightLoss  GoalType = "weight_loss"
+	GoalMaintenance GoalType = "maintenance"
+	GoalMuscleGain  GoalType = "muscle_gain"
+)
+
+// GoalTypes lists the valid goal types
+var GoalTypes = []GoalType{GoalWeightLoss, GoalMaintenance, GoalMuscleGain}
+
+const (
+	// kcalPerKg is the approximate energy content of a kilogram of body weight
+	kcalPerKg = 7700
+
+	// MaxGoalRate is the fastest weight change, in kg/week, a goal can target
+	MaxGoalRate = 1.0
+
+	// minCalories is the lowest daily calorie target ever suggested
+	minCalories = 1200
+
+	fatCaloriesShare = 0.25
+	fiberPer1000kcal = 14
+)
+
+// Goal is a diet goal with its parameters. Zero parameters fall back to the
+// defaults of the goal type.
+type Goal struct {
+	Type GoalType
+	// Rate is the targeted weight change in kg/week, always positive
+	Rate float64
+	// ProteinPerKg is the daily protein target in g per kg of body weight
+	ProteinPerKg float64
+}
+
+// IsValid reports whether the goal type is known
+func (t GoalType) IsValid() bool {
+	for _, goalType := range GoalTypes {
+		if t == goalType {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRate returns the weight change rate, applying the default of the
+// goal type when none is set
+func (g Goal) EffectiveRate() float64 {
+	switch g.Type {
+	case GoalWeightLoss:
+		if g.Rate > 0 {
+			return g.Rate
+		}
+		return 0.5
+	case GoalMuscleGain:
+		if g.Rate > 0 {
+			return g.Rate
+		}
+		return 0.25
+	default:
+		return 0
+	}
+}
+
+// WeeklyTarget returns the targeted weight change in kg/week, negative when
+// losing weight
+func (g Goal) WeeklyTarget() float64 {
+	if g.Type == GoalWeightLoss {
+		return -g.EffectiveRate()
+	}
+	return g.EffectiveRate()
+}
+
+// EffectiveProteinPerKg returns the protein target per kg, applying the
+// default of the goal type when none is set
+func (g Goal) EffectiveProteinPerKg() float64 {
+	if g.ProteinPerKg > 0 {
+		return g.ProteinPerKg
+	}
+	if g.Type == GoalMaintenance {
+		return 1.6
+	}
+	return 2.0
+}
+
+// CalculateTargets derives the daily nutrition targets from the TDEE and the
+// user's goal. They are all 0 when the user's measurements are unknown.
+func (u *User) CalculateTargets() NutritionalTotals {
+	if !u.HasMeasurements() {
+		return NutritionalTotals{}
+	}
+
+	dailyAdjustment := u.Goal.EffectiveRate() * kcalPerKg / 7
+	calories := u.CalculateTDEE()
+	switch u.Goal.Type {
+	case GoalWeightLoss:
+		calories -= dailyAdjustment
+	case GoalMuscleGain:
+		calories += dailyAdjustment
+	}
+	calories = math.Max(calories, minCalories)
+
+	proteins := u.Goal.EffectiveProteinPerKg() * u.Weight
+	fats := calories * fatCaloriesShare / 9
+	carbs := math.Max((calories-(proteins*4)-(fats*9))/4, 0)
+
+	return NutritionalTotals{
+		Calories: calories,
+		Proteins: proteins,
+		Carbs:    carbs,
+		Fats:     fats,
+		Fiber:    calories / 1000 * fiberPer1000kcal,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gtinLength is the length of GTIN-14 codes, to which shorter barcodes are
+// padded
+const gtinLength = 14
+
+// NormalizeGTIN validates a GTIN-8, UPC-A (GTIN-12), EAN-13 or GTIN-14
+// barcode and returns it as a GTIN-14, padded with leading zeros. Spaces and
+// dashes are ignored.
+func NormalizeGTIN(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("invalid barcode %q: expected 8, 12, 13 or 14 digits", code)
+	}
+
+	// The check digit makes the weighted sum of the digits, with weights
+	// alternating 3 and 1 from the right, a multiple of 10
+	sum := 0
+	for i := range code {
+		digit := code[len(code)-1-i]
+		if digit < '0' || digit > '9' {
+			return "", fmt.Errorf("invalid barcode %q: expected digits only", code)
+		}
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(digit-'0') * weight
+	}
+	if sum%10 != 0 {
+		return "", fmt.Errorf("invalid barcode %q: wrong check digit", code)
+	}
+	// Zeros pass the check, but are placeholders rather than products
+	if strings.Trim(code, "0") == "" {
+		return "", fmt.Errorf("invalid barcode %q: no product has an all-zero code", code)
+	}
+
+	return strings.Repeat("0", gtinLength-len(code)) + code, nil
+}
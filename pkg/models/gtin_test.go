@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"96385074", "00000096385074", false},      // GTIN-8
+		{"036000291452", "00036000291452", false},  // UPC-A
+		{"4006381333931", "04006381333931", false}, // EAN-13
+		{"10012345678902", "10012345678902", false},
+		{"00036000291452", "00036000291452", false},
+		{"0 36000 29145 2", "00036000291452", false},
+		{"400-6381-333931", "04006381333931", false},
+		{"00000000", "", true},       // all zeros
+		{"00000000000000", "", true}, // all zeros
+		{"036000291453", "", true},   // wrong check digit
+		{"4006381333932", "", true},  // wrong check digit
+		{"96385075", "", true},       // wrong check digit
+		{"3600029145", "", true},     // 10 digits
+		{"036000291452 1", "", true}, // 13 digits, wrong check digit
+		{"0360002914a2", "", true},
+		{"", "", true},
+		{"123456789012345", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeGTIN(tt.code)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeGTIN(%q) error = %v, want error %v", tt.code, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeGTIN(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrFoodNotFound is matched by the errors of food sources that do not know
+// a food
+var ErrFoodNotFound = errors.New("food not found")
 
 // Meal represents a meal with a list of foods
 type Meal struct {
@@ -24,6 +31,25 @@ type Food struct {
 	Carbs    float64
 	Fats     float64
 	Fiber    float64
+	// Nutrients holds every known nutrient per 100g keyed by FDC nutrient
+	// number, or is nil when only the macronutrients are known
+	Nutrients map[string]Nutrient
+
+	// Where the food comes from, with the label serving of branded products.
+	// They are empty when unknown.
+	DataType    DataType
+	BrandOwner  string
+	ServingSize float64 // g or ml
+	ServingText string  // household serving, such as "1 cup"
+	// GTIN is the barcode of branded products, normalized by NormalizeGTIN
+	GTIN string
+	// Portions are the household measures of the food, empty when unknown
+	Portions []Portion
+
+	// Version identifies the stored copy of the food data a meal item was
+	// logged with. It is 0 for foods that were not loaded from a log, whose
+	// data is taken as the latest.
+	Version int64
 }
 
 // AddFood adds a food item to the meal
@@ -34,8 +60,24 @@ func (m *Meal) AddFood(food *Food, quantity float64) {
 	})
 }
 
+// CopyTo returns a copy of the meal on another day, at the same time of day
+func (m *Meal) CopyTo(day time.Time) *Meal {
+	return &Meal{
+		Name:  m.Name,
+		Time:  time.Date(day.Year(), day.Month(), day.Day(), m.Time.Hour(), m.Time.Minute(), m.Time.Second(), 0, time.Local),
+		Foods: append(make([]FoodQuantity, 0, len(m.Foods)), m.Foods...),
+	}
+}
+
+// RemoveFood removes the food item at the given index and returns it
+func (m *Meal) RemoveFood(index int) FoodQuantity {
+	item := m.Foods[index]
+	m.Foods = append(m.Foods[:index], m.Foods[index+1:]...)
+	return item
+}
+
 func (m *Meal) CalculateTotals() NutritionalTotals {
-	var totals NutritionalTotals
+	totals := NutritionalTotals{Nutrients: map[string]Nutrient{}}
 	for _, item := range m.Foods {
 		multiplier := item.Quantity / 100 // Convert from per 100g to actual quantity
 		totals.Calories += item.Food.Calories * multiplier
@@ -43,6 +85,7 @@ func (m *Meal) CalculateTotals() NutritionalTotals {
 		totals.Carbs += item.Food.Carbs * multiplier
 		totals.Fats += item.Food.Fats * multiplier
 		totals.Fiber += item.Food.Fiber * multiplier
+		addNutrients(totals.Nutrients, item.Food.Nutrients, multiplier)
 	}
 	return totals
 }
@@ -53,4 +96,19 @@ type NutritionalTotals struct {
 	Carbs    float64
 	Fats     float64
 	Fiber    float64
+	// Nutrients holds the micronutrient totals keyed by FDC nutrient number
+	Nutrients map[string]Nutrient
+}
+
+// Add adds other totals to these ones
+func (t *NutritionalTotals) Add(other NutritionalTotals) {
+	t.Calories += other.Calories
+	t.Proteins += other.Proteins
+	t.Carbs += other.Carbs
+	t.Fats += other.Fats
+	t.Fiber += other.Fiber
+	if t.Nutrients == nil {
+		t.Nutrients = map[string]Nutrient{}
+	}
+	addNutrients(t.Nutrients, other.Nutrients, 1)
 }
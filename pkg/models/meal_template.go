@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// MealTemplate is a meal saved under a name, to log it again on any day
+type MealTemplate struct {
+	ID   int64
+	Name string
+	// Meal holds the name and foods of the meal, its time being unused
+	Meal Meal
+}
+
+// Instantiate returns a new meal made of the template foods, at a time
+func (t *MealTemplate) Instantiate(at time.Time) *Meal {
+	return &Meal{
+		Name:  t.Meal.Name,
+		Time:  at,
+		Foods: append(make([]FoodQuantity, 0, len(t.Meal.Foods)), t.Meal.Foods...),
+	}
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Nutrient is an amount of a nutrient, identified by its FDC nutrient number
+type Nutrient struct {
+	Number string
+	Name   string
+	Unit   string
+	Amount float64
+}
+
+// FDC numbers of the macronutrients, which are also stored in the Food fields
+const (
+	NutrientEnergy  = "208"
+	NutrientProtein = "203"
+	NutrientCarbs   = "205"
+	NutrientFat     = "204"
+	NutrientFiber   = "291"
+)
+
+// DefaultNutrients are the micronutrients shown in reports unless others
+// are requested
+var DefaultNutrients = []Nutrient{
+	{Number: "307", Name: "Sodium", Unit: "mg"},
+	{Number: "269", Name: "Sugars", Unit: "g"},
+	{Number: "606", Name: "Saturated fat", Unit: "g"},
+	{Number: "601", Name: "Cholesterol", Unit: "mg"},
+	{Number: "306", Name: "Potassium", Unit: "mg"},
+	{Number: "301", Name: "Calcium", Unit: "mg"},
+	{Number: "303", Name: "Iron", Unit: "mg"},
+	{Number: "320", Name: "Vitamin A", Unit: "µg"},
+	{Number: "401", Name: "Vitamin C", Unit: "mg"},
+	{Number: "328", Name: "Vitamin D", Unit: "µg"},
+}
+
+// Macronutrients describe the nutrients stored in the Food fields
+var Macronutrients = []Nutrient{
+	{Number: NutrientEnergy, Name: "Energy", Unit: "kcal"},
+	{Number: NutrientProtein, Name: "Protein", Unit: "g"},
+	{Number: NutrientCarbs, Name: "Carbohydrate, by difference", Unit: "g"},
+	{Number: NutrientFat, Name: "Total lipid (fat)", Unit: "g"},
+	{Number: NutrientFiber, Name: "Fiber, total dietary", Unit: "g"},
+}
+
+// LookupNutrient finds a nutrient by alias, such as "protein" or "sodium", or
+// by FDC nutrient number. The name and unit of the returned nutrient are only
+// set for the macronutrients and the default micronutrients.
+func LookupNutrient(name string) (Nutrient, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	number, ok := nutrientAliases[name]
+	if !ok {
+		if _, err := strconv.Atoi(name); err != nil {
+			return Nutrient{}, fmt.Errorf("unknown nutrient %q", name)
+		}
+		number = name
+	}
+
+	for _, known := range append(Macronutrients[:len(Macronutrients):len(Macronutrients)], DefaultNutrients...) {
+		if known.Number == number {
+			return known, nil
+		}
+	}
+	return Nutrient{Number: number}, nil
+}
+
+// energyFallbacks are the Atwater energy nutrients of Foundation foods,
+// which often lack the usual energy nutrient
+var energyFallbacks = []string{"958", "957"}
+
+// FillEnergy sets the energy of a food lacking it from its Atwater energy,
+// when known
+func (f *Food) FillEnergy() {
+	if _, ok := f.Nutrients[NutrientEnergy]; ok {
+		return
+	}
+	for _, number := range energyFallbacks {
+		if n, ok := f.Nutrients[number]; ok {
+			f.SetNutrient(Nutrient{Number: NutrientEnergy, Name: "Energy", Unit: "kcal", Amount: n.Amount})
+			return
+		}
+	}
+}
+
+// SetNutrient records a nutrient amount per 100g, also filling the matching
+// field when it is a macronutrient
+func (f *Food) SetNutrient(n Nutrient) {
+	if f.Nutrients == nil {
+		f.Nutrients = make(map[string]Nutrient)
+	}
+	f.Nutrients[n.Number] = n
+
+	switch n.Number {
+	case NutrientEnergy:
+		f.Calories = n.Amount
+	case NutrientProtein:
+		f.Proteins = n.Amount
+	case NutrientCarbs:
+		f.Carbs = n.Amount
+	case NutrientFat:
+		f.Fats = n.Amount
+	case NutrientFiber:
+		f.Fiber = n.Amount
+	}
+}
+
+// addNutrients adds scaled nutrient amounts to a map keyed by nutrient number
+func addNutrients(dst map[string]Nutrient, src map[string]Nutrient, multiplier float64) {
+	for number, n := range src {
+		total, ok := dst[number]
+		if !ok {
+			total = Nutrient{Number: n.Number, Name: n.Name, Unit: n.Unit}
+		}
+		total.Amount += n.Amount * multiplier
+		dst[number] = total
+	}
+}
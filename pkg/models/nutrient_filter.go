@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// nutrientAliases are the names accepted by nutrient filters and
+// LookupNutrient besides FDC nutrient numbers
+var nutrientAliases = map[string]string{
+	"kcal":          NutrientEnergy,
+	"calories":      NutrientEnergy,
+	"energy":        NutrientEnergy,
+	"protein":       NutrientProtein,
+	"proteins":      NutrientProtein,
+	"carbs":         NutrientCarbs,
+	"fat":           NutrientFat,
+	"fats":          NutrientFat,
+	"fiber":         NutrientFiber,
+	"sugar":         "269",
+	"sugars":        "269",
+	"sodium":        "307",
+	"saturated fat": "606",
+	"cholesterol":   "601",
+	"potassium":     "306",
+	"calcium":       "301",
+	"iron":          "303",
+	"vitamin a":     "320",
+	"vitamin c":     "401",
+	"vitamin d":     "328",
+}
+
+// filterOperators are the comparisons of nutrient filters, two character
+// operators first so that they are matched before their prefix
+var filterOperators = []string{"<=", ">=", "<", ">", "="}
+
+// NutrientFilter is a constraint on the amount of a nutrient per 100g, such
+// as "protein > 20"
+type NutrientFilter struct {
+	Number   string
+	Operator string
+	Value    float64
+}
+
+// ParseNutrientFilter parses a filter such as "protein > 20" or "kcal<150".
+// The nutrient is an alias such as "protein", or an FDC nutrient number.
+func ParseNutrientFilter(s string) (NutrientFilter, error) {
+	for _, operator := range filterOperators {
+		name, value, ok := strings.Cut(s, operator)
+		if !ok {
+			continue
+		}
+
+		n, err := LookupNutrient(name)
+		if err != nil {
+			return NutrientFilter{}, err
+		}
+		number := n.Number
+
+		amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return NutrientFilter{}, fmt.Errorf("invalid amount %q", strings.TrimSpace(value))
+		}
+		return NutrientFilter{Number: number, Operator: operator, Value: amount}, nil
+	}
+	return NutrientFilter{}, fmt.Errorf("invalid filter %q, expected e.g. \"protein > 20\"", s)
+}
+
+// Matches reports whether the food satisfies the filter. Unknown nutrient
+// amounts count as 0.
+func (f NutrientFilter) Matches(food Food) bool {
+	amount := food.Nutrients[f.Number].Amount
+	switch f.Number {
+	case NutrientEnergy:
+		amount = food.Calories
+	case NutrientProtein:
+		amount = food.Proteins
+	case NutrientCarbs:
+		amount = food.Carbs
+	case NutrientFat:
+		amount = food.Fats
+	case NutrientFiber:
+		amount = food.Fiber
+	}
+
+	switch f.Operator {
+	case "<":
+		return amount < f.Value
+	case "<=":
+		return amount <= f.Value
+	case ">":
+		return amount > f.Value
+	case ">=":
+		return amount >= f.Value
+	default:
+		return amount == f.Value
+	}
+}
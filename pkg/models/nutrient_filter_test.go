@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestParseNutrientFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    NutrientFilter
+		wantErr bool
+	}{
+		{"protein > 20", NutrientFilter{Number: NutrientProtein, Operator: ">", Value: 20}, false},
+		{"kcal<150", NutrientFilter{Number: NutrientEnergy, Operator: "<", Value: 150}, false},
+		{"fat <= 3.5", NutrientFilter{Number: NutrientFat, Operator: "<=", Value: 3.5}, false},
+		{"fiber>=5", NutrientFilter{Number: NutrientFiber, Operator: ">=", Value: 5}, false},
+		{"Sodium = 0", NutrientFilter{Number: "307", Operator: "=", Value: 0}, false},
+		{"vitamin c > 10", NutrientFilter{Number: "401", Operator: ">", Value: 10}, false},
+		{"saturated fat < 1", NutrientFilter{Number: "606", Operator: "<", Value: 1}, false},
+		{"1003 > 2", NutrientFilter{Number: "1003", Operator: ">", Value: 2}, false},
+		{"protein", NutrientFilter{}, true},
+		{"protein > lots", NutrientFilter{}, true},
+		{"protein >", NutrientFilter{}, true},
+		{"unobtainium > 1", NutrientFilter{}, true},
+		{"> 20", NutrientFilter{}, true},
+		{"", NutrientFilter{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNutrientFilter(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseNutrientFilter(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseNutrientFilter(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNutrientFilterMatches(t *testing.T) {
+	food := Food{Calories: 120, Proteins: 20, Fats: 3}
+	food.SetNutrient(Nutrient{Number: "307", Amount: 400})
+
+	tests := []struct {
+		filter NutrientFilter
+		want   bool
+	}{
+		{NutrientFilter{Number: NutrientProtein, Operator: ">", Value: 20}, false},
+		{NutrientFilter{Number: NutrientProtein, Operator: ">=", Value: 20}, true},
+		{NutrientFilter{Number: NutrientEnergy, Operator: "<", Value: 150}, true},
+		{NutrientFilter{Number: NutrientFat, Operator: "<=", Value: 3}, true},
+		{NutrientFilter{Number: NutrientCarbs, Operator: "=", Value: 0}, true},
+		{NutrientFilter{Number: "307", Operator: "<", Value: 300}, false},
+		{NutrientFilter{Number: "401", Operator: "<", Value: 1}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Matches(food); got != tt.want {
+			t.Errorf("%+v.Matches() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Portion is a household measure of a food, such as "1 cup, chopped" or
+// "1 large"
+type Portion struct {
+	Amount      float64 // of the measure, such as 0.5 for half a cup
+	Description string  // the measure, such as "cup, chopped"
+	GramWeight  float64 // of Amount measures
+}
+
+// Measure splits the portion into its amount and unit. FDC describes some
+// portions with their amount, as in "1 cup", which then wins over Amount.
+func (p Portion) Measure() (float64, string) {
+	if strings.IndexFunc(p.Description, unicode.IsDigit) == 0 {
+		amount, unit, err := splitQuantity(p.Description)
+		if err == nil && amount > 0 && unit != "" {
+			return amount, unit
+		}
+	}
+	if p.Amount > 0 {
+		return p.Amount, p.Description
+	}
+	return 1, p.Description
+}
+
+// Name describes the portion with its amount, as in "1 cup, chopped"
+func (p Portion) Name() string {
+	amount, unit := p.Measure()
+	return strconv.FormatFloat(amount, 'f', -1, 64) + " " + unit
+}
+
+// weightUnits are the units converted to grams without the food portions
+var weightUnits = map[string]float64{
+	"g": 1, "gram": 1,
+	"kg": 1000, "kilogram": 1000,
+	"oz": 28.3495, "ounce": 28.3495,
+	"lb": 453.592, "pound": 453.592,
+}
+
+// unitAliases are the abbreviations of the household measures
+var unitAliases = map[string]string{
+	"tbsp": "tablespoon",
+	"tbs":  "tablespoon",
+	"tsp":  "teaspoon",
+	"pc":   "piece",
+	"lbs":  "lb",
+}
+
+// ParseQuantity converts a quantity of the food, such as "150", "150g",
+// "8 oz", "2 slices", "1/2 cup", "1 1/2 cups" or "1 serving", to grams. Household measures
+// are matched against the food portions and its label serving.
+func (f *Food) ParseQuantity(s string) (float64, error) {
+	amount, unit, err := splitQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("quantity must be positive")
+	}
+
+	words := unitWords(unit)
+	if len(words) == 0 {
+		return amount, nil
+	}
+	if grams, ok := weightUnits[strings.Join(words, " ")]; ok {
+		return amount * grams, nil
+	}
+
+	if portion, ok := f.findPortion(words); ok {
+		portionAmount, _ := portion.Measure()
+		return amount / portionAmount * portion.GramWeight, nil
+	}
+
+	known := []string{"g", "kg", "oz", "lb"}
+	for _, portion := range f.householdPortions() {
+		_, portionUnit := portion.Measure()
+		if name := strings.Join(unitWords(portionUnit), " "); !slices.Contains(known, name) {
+			known = append(known, name)
+		}
+	}
+	return 0, fmt.Errorf("unknown unit %q for %s, use one of: %s", unit, f.Name, strings.Join(known, ", "))
+}
+
+// householdPortions returns the portions of the food, followed by its label
+// serving
+func (f *Food) householdPortions() []Portion {
+	portions := f.Portions
+	if f.ServingSize > 0 {
+		portions = append(portions[:len(portions):len(portions)], Portion{Amount: 1, Description: "serving", GramWeight: f.ServingSize})
+		if f.ServingText != "" {
+			portions = append(portions, Portion{Amount: 1, Description: f.ServingText, GramWeight: f.ServingSize})
+		}
+	}
+	return portions
+}
+
+// findPortion finds the portion measured in a unit. A portion whose unit is
+// the given one wins over those starting with it, as "cup, chopped" for
+// "cup", then over those the unit starts with, as "large" for "large egg".
+func (f *Food) findPortion(words []string) (Portion, bool) {
+	var found Portion
+	rank := 0
+	for _, portion := range f.householdPortions() {
+		if portion.GramWeight <= 0 {
+			continue
+		}
+		_, unit := portion.Measure()
+		portionWords := unitWords(unit)
+		if len(portionWords) == 0 {
+			continue
+		}
+
+		switch {
+		case wordsHavePrefix(portionWords, words) && len(portionWords) == len(words):
+			return portion, true
+		case wordsHavePrefix(portionWords, words) && rank < 2:
+			found, rank = portion, 2
+		case wordsHavePrefix(words, portionWords) && rank < 1:
+			found, rank = portion, 1
+		}
+	}
+	return found, rank > 0
+}
+
+func wordsHavePrefix(words, prefix []string) bool {
+	if len(prefix) > len(words) {
+		return false
+	}
+	for i := range prefix {
+		if words[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitQuantity splits a quantity such as "1.5 cup", "1/2cup" or "1 1/2
+// cups" into its amount and unit. The amount defaults to 1 when the quantity
+// starts with its unit.
+func splitQuantity(s string) (float64, string, error) {
+	number, unit := splitNumber(strings.TrimSpace(s))
+	if number == "" {
+		if unit == "" {
+			return 0, "", fmt.Errorf("missing quantity")
+		}
+		return 1, unit, nil
+	}
+
+	amount, err := parseAmount(number)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid quantity %q", s)
+	}
+	// A whole number may be followed by a fraction, as in "1 1/2"
+	if fraction, rest := splitNumber(unit); isWholeNumber(number) && strings.Contains(fraction, "/") {
+		part, err := parseAmount(fraction)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid quantity %q", s)
+		}
+		amount, unit = amount+part, rest
+	}
+	return amount, unit, nil
+}
+
+// splitNumber splits the leading number, decimal or fraction, of a quantity
+// from the rest of it
+func splitNumber(s string) (string, string) {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != '/'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	return s[:end], strings.TrimSpace(s[end:])
+}
+
+// parseAmount parses a decimal number or a fraction, as "1/2"
+func parseAmount(number string) (float64, error) {
+	numerator, denominator, ok := strings.Cut(number, "/")
+	if !ok {
+		return strconv.ParseFloat(number, 64)
+	}
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, err
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil {
+		return 0, err
+	}
+	if d == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return n / d, nil
+}
+
+func isWholeNumber(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+// unitWords normalizes a unit into lower case singular words, without
+// punctuation, so that "Slices" matches "slice," and "tbsp" "tablespoon"
+func unitWords(unit string) []string {
+	words := strings.FieldsFunc(strings.ToLower(unit), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if alias, ok := unitAliases[word]; ok {
+			word = alias
+		}
+		words[i] = singular(word)
+	}
+	return words
+}
+
+// singular returns the singular of an English plural, as "slice" for
+// "slices", or the word itself
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") ||
+		strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "oes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 2:
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSplitQuantity(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantAmount float64
+		wantUnit   string
+		wantErr    bool
+	}{
+		{"150", 150, "", false},
+		{"150g", 150, "g", false},
+		{"8 oz", 8, "oz", false},
+		{"1.5 cup", 1.5, "cup", false},
+		{"1/2cup", 0.5, "cup", false},
+		{"1/2 cup", 0.5, "cup", false},
+		{"1 1/2 cups", 1.5, "cups", false},
+		{"2 3/4", 2.75, "", false},
+		{"1 1/2cup", 1.5, "cup", false},
+		{"2 100g packs", 2, "100g packs", false},
+		{"  3 slices  ", 3, "slices", false},
+		{"slice", 1, "slice", false},
+		{"large egg", 1, "large egg", false},
+		{"", 0, "", true},
+		{"1/0 cup", 0, "", true},
+		{"1 1/0 cup", 0, "", true},
+		{"1..5 cup", 0, "", true},
+		{"1/2/3 cup", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		amount, unit, err := splitQuantity(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitQuantity(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if math.Abs(amount-tt.wantAmount) > 1e-9 || unit != tt.wantUnit {
+			t.Errorf("splitQuantity(%q) = %v, %q, want %v, %q", tt.in, amount, unit, tt.wantAmount, tt.wantUnit)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	bread := &Food{
+		Name: "Bread, whole-wheat",
+		Portions: []Portion{
+			{Amount: 1, Description: "slice", GramWeight: 32},
+			{Amount: 1, Description: "cup, cubes", GramWeight: 45},
+			{Amount: 1, Description: "cup, crumbs", GramWeight: 108},
+			{Amount: 0, Description: "1 oz", GramWeight: 28.35},
+		},
+	}
+	egg := &Food{
+		Name:     "Egg, whole, raw",
+		Portions: []Portion{{Amount: 1, Description: "large", GramWeight: 50}, {Amount: 1, Description: "cup", GramWeight: 243}},
+	}
+	bar := &Food{Name: "Granola bar", ServingSize: 40, ServingText: "1 bar"}
+
+	tests := []struct {
+		food    *Food
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{bread, "150", 150, false},
+		{bread, "150g", 150, false},
+		{bread, "0.2 kg", 200, false},
+		{bread, "8 oz", 226.796, false},
+		{bread, "1 lb", 453.592, false},
+		{bread, "2 slices", 64, false},
+		{bread, "2 Slices", 64, false},
+		{bread, "1/2 slice", 16, false},
+		{bread, "1 1/2 slices", 48, false},
+		{bread, "1 cup", 45, false},
+		{bread, "1 cup crumbs", 108, false},
+		{egg, "2 large", 100, false},
+		{egg, "2 large eggs", 100, false},
+		{egg, "1 1/2 cups", 364.5, false},
+		{bar, "2 serving", 80, false},
+		{bar, "1 bar", 40, false},
+		{bread, "0", 0, true},
+		{bread, "-1 slice", 0, true},
+		{bread, "2 handfuls", 0, true},
+		{bar, "1 slice", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.food.ParseQuantity(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseQuantity(%q) error = %v, want error %v", tt.food.Name, tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: ParseQuantity(%q) = %v, want %v", tt.food.Name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPortionMeasure(t *testing.T) {
+	tests := []struct {
+		portion    Portion
+		wantAmount float64
+		wantUnit   string
+	}{
+		{Portion{Amount: 1, Description: "cup, chopped"}, 1, "cup, chopped"},
+		{Portion{Amount: 0.5, Description: "cup"}, 0.5, "cup"},
+		{Portion{Amount: 1, Description: "1 1/2 cups"}, 1.5, "cups"},
+		{Portion{Amount: 0, Description: "large"}, 1, "large"},
+		{Portion{Amount: 2, Description: "3"}, 2, "3"},
+	}
+
+	for _, tt := range tests {
+		amount, unit := tt.portion.Measure()
+		if amount != tt.wantAmount || unit != tt.wantUnit {
+			t.Errorf("%+v.Measure() = %v, %q, want %v, %q", tt.portion, amount, unit, tt.wantAmount, tt.wantUnit)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+// Recipe is a dish made of several foods, which can be eaten like a food
+type Recipe struct {
+	ID       string
+	Name     string
+	Servings float64
+	// YieldWeight is the cooked weight in grams, or 0 when it is the weight
+	// of the raw ingredients
+	YieldWeight float64
+	Ingredients []FoodQuantity
+}
+
+// RawWeight returns the total weight of the ingredients, in grams
+func (r *Recipe) RawWeight() float64 {
+	var weight float64
+	for _, item := range r.Ingredients {
+		weight += item.Quantity
+	}
+	return weight
+}
+
+// CookedWeight returns the weight of the dish, in grams
+func (r *Recipe) CookedWeight() float64 {
+	if r.YieldWeight > 0 {
+		return r.YieldWeight
+	}
+	return r.RawWeight()
+}
+
+// CalculateTotals sums the nutritional values of the whole dish
+func (r *Recipe) CalculateTotals() NutritionalTotals {
+	meal := Meal{Foods: r.Ingredients}
+	return meal.CalculateTotals()
+}
+
+// ToFood describes the dish as a food, with its nutrients per 100g of the
+// cooked dish and a serving being a share of it
+func (r *Recipe) ToFood() *Food {
+	food := &Food{
+		ID:        r.ID,
+		Name:      r.Name,
+		Nutrients: map[string]Nutrient{},
+		DataType:  DataTypeRecipe,
+		Portions:  []Portion{},
+	}
+
+	weight := r.CookedWeight()
+	if weight <= 0 {
+		return food
+	}
+	if r.Servings > 0 {
+		food.ServingSize = weight / r.Servings
+	}
+	food.Portions = append(food.Portions, Portion{Amount: 1, Description: "recipe", GramWeight: weight})
+
+	totals := r.CalculateTotals()
+	multiplier := 100 / weight
+	for _, n := range totals.Nutrients {
+		n.Amount *= multiplier
+		food.SetNutrient(n)
+	}
+	// The fields are also known for the foods without nutrients
+	macros := []float64{totals.Calories, totals.Proteins, totals.Carbs, totals.Fats, totals.Fiber}
+	for i, n := range Macronutrients {
+		n.Amount = macros[i] * multiplier
+		food.SetNutrient(n)
+	}
+	return food
+}
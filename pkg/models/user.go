@@ -1,19 +1,75 @@
 package models
 
+import "math"
+
+// ActivityLevel describes how physically active a user is day to day
+type ActivityLevel string
+
+const (
+	ActivitySedentary  ActivityLevel = "sedentary"
+	ActivityLight      ActivityLevel = "light"
+	ActivityModerate   ActivityLevel = "moderate"
+	ActivityActive     ActivityLevel = "active"
+	ActivityVeryActive ActivityLevel = "very_active"
+)
+
+// activityFactors are the multipliers applied to the BMR to get the TDEE
+var activityFactors = map[ActivityLevel]float64{
+	ActivitySedentary:  1.2,
+	ActivityLight:      1.375,
+	ActivityModerate:   1.55,
+	ActivityActive:     1.725,
+	ActivityVeryActive: 1.9,
+}
+
+// ActivityLevels lists the valid activity levels, from least to most active
+var ActivityLevels = []ActivityLevel{
+	ActivitySedentary,
+	ActivityLight,
+	ActivityModerate,
+	ActivityActive,
+	ActivityVeryActive,
+}
+
+// Factor returns the activity multiplier, or 0 if the level is unknown
+func (a ActivityLevel) Factor() float64 {
+	return activityFactors[a]
+}
+
 // User represents a user profile
 type User struct {
-	FirstName string
-	LastName  string
-	Age       int
-	Weight    float64
-	Height    float64
-	Gender    string
-	Goal      string
-	DailyLog  *DailyLog
+	ID            int64
+	FirstName     string
+	LastName      string
+	Age           int
+	Weight        float64
+	Height        float64
+	Gender        string
+	Goal          Goal
+	ActivityLevel ActivityLevel
+	DailyLog      *DailyLog
+
+	// Latest body measurements, 0 when unknown. Weight, height and age are
+	// also 0 when unknown, as for the placeholder profile of migrated logs,
+	// and the estimates needing them are then 0 too.
+	Waist   float64
+	Hip     float64
+	Neck    float64
+	BodyFat float64
 }
 
-// CalculateBMI calculates the user's BMI
+// HasMeasurements reports whether the weight, height and age of the user are
+// known
+func (u *User) HasMeasurements() bool {
+	return u.Weight > 0 && u.Height > 0 && u.Age > 0
+}
+
+// CalculateBMI calculates the user's BMI, or 0 when the weight or height is
+// unknown
 func (u *User) CalculateBMI() float64 {
+	if u.Weight <= 0 || u.Height <= 0 {
+		return 0
+	}
 	heightInMeters := u.Height / 100
 	return u.Weight / (heightInMeters * heightInMeters)
 }
@@ -21,6 +77,9 @@ func (u *User) CalculateBMI() float64 {
 // CalculateBodyFat estimates body fat percentage using BMI
 func (u *User) CalculateBodyFat() float64 {
 	bmi := u.CalculateBMI()
+	if bmi <= 0 {
+		return 0
+	}
 	age := float64(u.Age)
 	genderFactor := 0.0
 	if u.Gender == "male" {
@@ -31,7 +90,16 @@ func (u *User) CalculateBodyFat() float64 {
 	return (1.20 * bmi) + (0.23 * age) - (10.8 * genderFactor) - 5.4
 }
 
+// EstimateBodyFat returns the measured body fat if known, the US Navy
+// estimate if the needed measurements are known, or a BMI based estimate
 func (u *User) EstimateBodyFat() float64 {
+	if u.BodyFat > 0 {
+		return u.BodyFat
+	}
+	if navy := u.navyBodyFat(); navy > 0 {
+		return navy
+	}
+
 	bmi := u.CalculateBMI()
 	if bmi <= 0 {
 		return 0
@@ -43,3 +111,80 @@ func (u *User) EstimateBodyFat() float64 {
 	}
 	return (1.20 * bmi) + (0.23 * float64(u.Age)) - 5.4
 }
+
+// navyBodyFat estimates body fat with the US Navy method, returning 0 when
+// a measurement it needs is missing or the estimate is not a percentage
+func (u *User) navyBodyFat() float64 {
+	bodyFat := u.navyFormula()
+	if math.IsNaN(bodyFat) || bodyFat <= 0 || bodyFat >= 100 {
+		return 0
+	}
+	return bodyFat
+}
+
+// navyFormula applies the US Navy formula of the user's gender
+func (u *User) navyFormula() float64 {
+	if u.Waist <= 0 || u.Neck <= 0 || u.Height <= 0 {
+		return 0
+	}
+
+	if u.Gender == "male" {
+		if u.Waist <= u.Neck {
+			return 0
+		}
+		return 495/(1.0324-0.19077*math.Log10(u.Waist-u.Neck)+0.15456*math.Log10(u.Height)) - 450
+	}
+
+	if u.Hip <= 0 || u.Waist+u.Hip <= u.Neck {
+		return 0
+	}
+	return 495/(1.29579-0.35004*math.Log10(u.Waist+u.Hip-u.Neck)+0.22100*math.Log10(u.Height)) - 450
+}
+
+// CalculateBMR estimates the basal metabolic rate in kcal/day using the
+// Mifflin-St Jeor equation, or 0 when the measurements are unknown
+func (u *User) CalculateBMR() float64 {
+	if !u.HasMeasurements() {
+		return 0
+	}
+	bmr := (10 * u.Weight) + (6.25 * u.Height) - (5 * float64(u.Age))
+	if u.Gender == "male" {
+		return bmr + 5
+	}
+	return bmr - 161
+}
+
+// CalculateBMRHarrisBenedict estimates the basal metabolic rate in kcal/day
+// using the revised Harris-Benedict equation, or 0 when the measurements are
+// unknown
+func (u *User) CalculateBMRHarrisBenedict() float64 {
+	if !u.HasMeasurements() {
+		return 0
+	}
+	age := float64(u.Age)
+	if u.Gender == "male" {
+		return 88.362 + (13.397 * u.Weight) + (4.799 * u.Height) - (5.677 * age)
+	}
+	return 447.593 + (9.247 * u.Weight) + (3.098 * u.Height) - (4.330 * age)
+}
+
+// CalculateBMRKatchMcArdle estimates the basal metabolic rate in kcal/day
+// from the lean body mass, using the estimated body fat, or 0 when the
+// measurements are unknown
+func (u *User) CalculateBMRKatchMcArdle() float64 {
+	if !u.HasMeasurements() {
+		return 0
+	}
+	leanMass := u.Weight * (1 - u.EstimateBodyFat()/100)
+	return 370 + (21.6 * leanMass)
+}
+
+// CalculateTDEE estimates the total daily energy expenditure in kcal/day,
+// treating an unknown activity level as sedentary
+func (u *User) CalculateTDEE() float64 {
+	factor := u.ActivityLevel.Factor()
+	if factor == 0 {
+		factor = ActivitySedentary.Factor()
+	}
+	return u.CalculateBMR() * factor
+}
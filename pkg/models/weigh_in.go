@@ -0,0 +1,74 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// WeighIn is a dated body weight measurement. Optional measurements are 0
+// when they were not taken.
+type WeighIn struct {
+	Date    time.Time
+	Weight  float64 // kg
+	Waist   float64 // cm
+	Hip     float64 // cm
+	Neck    float64 // cm
+	BodyFat float64 // percentage
+}
+
+// TrendWindow is the number of days averaged by the weight trend
+const TrendWindow = 7
+
+// ApplyWeighIn updates the user's body measurements from a weigh-in
+func (u *User) ApplyWeighIn(w WeighIn) {
+	u.Weight = w.Weight
+	u.Waist = w.Waist
+	u.Hip = w.Hip
+	u.Neck = w.Neck
+	u.BodyFat = w.BodyFat
+}
+
+// WeightTrend returns, for each weigh-in, the average weight over the
+// TrendWindow days ending on its date. Weigh-ins must be sorted by date.
+func WeightTrend(weighIns []WeighIn) []float64 {
+	trend := make([]float64, len(weighIns))
+	start := 0
+	for i, w := range weighIns {
+		windowStart := w.Date.AddDate(0, 0, -TrendWindow+1)
+		for weighIns[start].Date.Before(windowStart) {
+			start++
+		}
+
+		var sum float64
+		for _, entry := range weighIns[start : i+1] {
+			sum += entry.Weight
+		}
+		trend[i] = sum / float64(i+1-start)
+	}
+	return trend
+}
+
+// WeeklyChange estimates the weight change in kg/week with a least squares
+// fit over the weigh-ins. It returns 0 with fewer than two weigh-ins.
+func WeeklyChange(weighIns []WeighIn) float64 {
+	if len(weighIns) < 2 {
+		return 0
+	}
+
+	origin := weighIns[0].Date
+	n := float64(len(weighIns))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, w := range weighIns {
+		x := w.Date.Sub(origin).Hours() / 24
+		sumX += x
+		sumY += w.Weight
+		sumXY += x * w.Weight
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if math.Abs(denominator) < 1e-9 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator * 7
+}
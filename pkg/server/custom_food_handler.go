@@ -0,0 +1,156 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"sort"
+	"strings"
+)
+
+func (s *Server) handleCreateCustomFood(untypedData any) Response {
+	data, ok := untypedData.(CustomFoodData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.customFoods()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	food, err := newCustomFood(data)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if err := store.CreateFood(food); err != nil {
+		return Response{Error: fmt.Errorf("failed to create food: %v", err)}
+	}
+
+	food.ID = source.Name() + foodIDSeparator + food.ID
+	return Response{Data: CustomFoodResponse{Food: newCustomFoodInfo(*food)}}
+}
+
+func (s *Server) handleUpdateCustomFood(untypedData any) Response {
+	data, ok := untypedData.(CustomFoodData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.customFoods()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	sourceName, sourceID, ok := strings.Cut(data.ID, foodIDSeparator)
+	if !ok || sourceName != source.Name() {
+		return Response{Error: invalidf("only custom foods can be edited, not %q", data.ID)}
+	}
+
+	food, err := newCustomFood(data)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	food.ID = sourceID
+	err = store.UpdateFood(food)
+	if errors.Is(err, models.ErrFoodNotFound) {
+		return Response{Error: notFoundf("unknown food %q", data.ID)}
+	}
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to update food: %v", err)}
+	}
+
+	food.ID = data.ID
+	food.DataType = models.DataTypeCustom
+	return Response{Data: CustomFoodResponse{Food: newCustomFoodInfo(*food)}}
+}
+
+func (s *Server) handleListCustomFoods(untypedData any) Response {
+	if _, ok := untypedData.(ListCustomFoodsData); !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.customFoods()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	foods, err := store.ListFoods()
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to list custom foods: %v", err)}
+	}
+
+	infos := make([]CustomFoodInfo, 0, len(foods))
+	for _, food := range foods {
+		food.ID = source.Name() + foodIDSeparator + food.ID
+		infos = append(infos, newCustomFoodInfo(food))
+	}
+	return Response{Data: CustomFoodListResponse{Foods: infos}}
+}
+
+// newCustomFood validates a custom food request and converts its nutrients
+// to amounts per 100g
+func newCustomFood(data CustomFoodData) (*models.Food, error) {
+	food := &models.Food{
+		Name:        strings.TrimSpace(data.Name),
+		BrandOwner:  strings.TrimSpace(data.BrandOwner),
+		ServingSize: data.ServingSize,
+		ServingText: strings.TrimSpace(data.ServingText),
+		Nutrients:   map[string]models.Nutrient{},
+		Portions:    []models.Portion{},
+	}
+	if food.Name == "" {
+		return nil, invalidf("name is required")
+	}
+	if food.ServingSize < 0 {
+		return nil, invalidf("serving size cannot be negative")
+	}
+
+	multiplier := 1.0
+	if data.PerServing {
+		if food.ServingSize <= 0 {
+			return nil, invalidf("nutrients per serving need a serving size")
+		}
+		multiplier = 100 / food.ServingSize
+	}
+	for name, amount := range data.Nutrients {
+		n, err := models.LookupNutrient(name)
+		if err != nil {
+			return nil, invalidf("%v", err)
+		}
+		if amount < 0 {
+			return nil, invalidf("%s cannot be negative", name)
+		}
+		if n.Unit == "" {
+			return nil, invalidf("nutrient %s is not supported for custom foods", name)
+		}
+		n.Amount = amount * multiplier
+		food.SetNutrient(n)
+	}
+
+	for _, p := range data.Portions {
+		description := strings.TrimSpace(p.Name)
+		if description == "" || p.Grams <= 0 {
+			return nil, invalidf("portions need a name and a positive weight")
+		}
+		food.Portions = append(food.Portions, models.Portion{Amount: 1, Description: description, GramWeight: p.Grams})
+	}
+	return food, nil
+}
+
+// newCustomFoodInfo describes a custom food, listing its other nutrients by
+// name
+func newCustomFoodInfo(food models.Food) CustomFoodInfo {
+	var nutrients []models.Nutrient
+	for _, n := range food.Nutrients {
+		if !isMacronutrient(n.Number) {
+			nutrients = append(nutrients, n)
+		}
+	}
+	sort.Slice(nutrients, func(i, j int) bool {
+		return nutrients[i].Name < nutrients[j].Name
+	})
+	return CustomFoodInfo{FoodItem: newFoodItem(food), Nutrients: newNutrientValues(nutrients, 0)}
+}
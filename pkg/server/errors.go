@@ -0,0 +1,51 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind classifies why a request failed
+type ErrorKind int
+
+const (
+	ErrInternal ErrorKind = iota
+	ErrInvalid
+	ErrNotFound
+	ErrUpstream
+)
+
+// Error is a request failure tagged with its kind
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of a request error, ErrInternal if it has none
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ErrInternal
+}
+
+func invalidf(format string, args ...any) error {
+	return &Error{Kind: ErrInvalid, Err: fmt.Errorf(format, args...)}
+}
+
+func notFoundf(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Err: fmt.Errorf(format, args...)}
+}
+
+func upstreamf(format string, args ...any) error {
+	return &Error{Kind: ErrUpstream, Err: fmt.Errorf(format, args...)}
+}
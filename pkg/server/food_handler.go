@@ -1,61 +1,261 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"nutritionapp/pkg/models"
 )
 
-func (s *Server) handleSearchFood(untypedData any) Response {
+func (s *Server) handleSearchFood(ctx context.Context, untypedData any) Response {
 	data, ok := untypedData.(SearchFoodData)
 	if !ok {
-		return Response{Error: fmt.Errorf("invalid request data")}
+		return Response{Error: invalidf("invalid request data")}
 	}
 
-	foods, err := s.foodProcessor.SearchFoods(data.Query)
+	query := models.FoodQuery{Text: data.Query, Page: data.Page, PageSize: data.PageSize, Sort: data.Sort}
+	for _, name := range data.DataTypes {
+		dataType := models.DataType(name)
+		if !dataType.IsValid() {
+			return Response{Error: invalidf("invalid data type %q (valid: %v)", name, models.DataTypes)}
+		}
+		query.DataTypes = append(query.DataTypes, dataType)
+	}
+	if query.Sort != "" && query.Sort != models.SortRelevance && query.Sort != models.SortName {
+		return Response{Error: invalidf("invalid sort %q (valid: %s, %s)", query.Sort, models.SortRelevance, models.SortName)}
+	}
+	query = query.Normalize()
+	if query.Page > models.MaxResults/query.PageSize {
+		return Response{Error: invalidf("page %d is past the first %d results, refine the search instead", query.Page, models.MaxResults)}
+	}
+
+	var filters []models.NutrientFilter
+	for _, text := range data.Filters {
+		filter, err := models.ParseNutrientFilter(text)
+		if err != nil {
+			return Response{Error: invalidf("%v", err)}
+		}
+		filters = append(filters, filter)
+	}
+
+	result, filtered, err := s.searchFoods(ctx, query, filters)
 	if err != nil {
-		return Response{Error: fmt.Errorf("search failed: %v", err)}
+		return Response{Error: upstreamf("search failed: %v", err)}
+	}
+
+	resp := SearchFoodResponseData{
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalHits:  result.TotalHits,
+		TotalPages: min((result.TotalHits+query.PageSize-1)/query.PageSize, models.MaxResults/query.PageSize),
+		Filtered:   filtered,
+	}
+	for _, f := range result.Foods {
+		resp.Foods = append(resp.Foods, newFoodItem(f))
+	}
+
+	return Response{Data: resp}
+}
+
+func (s *Server) handleLookupBarcode(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(LookupBarcodeData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	gtin, err := models.NormalizeGTIN(data.Code)
+	if err != nil {
+		return Response{Error: invalidf("%v", err)}
+	}
+
+	food, err := s.lookupBarcode(ctx, gtin)
+	if err != nil {
+		return Response{Error: err}
+	}
+	return Response{Data: LookupBarcodeResponse{Food: newFoodItem(*food)}}
+}
+
+func newFoodItem(f models.Food) FoodItem {
+	return FoodItem{
+		ID:       f.ID,
+		Name:     f.Name,
+		Calories: f.Calories,
+		Proteins: f.Proteins,
+		Carbs:    f.Carbs,
+		Fats:     f.Fats,
+		Fiber:    f.Fiber,
+
+		DataType:    string(f.DataType),
+		BrandOwner:  f.BrandOwner,
+		ServingSize: f.ServingSize,
+		ServingText: f.ServingText,
+		GTIN:        f.GTIN,
+		Portions:    newPortionInfos(f.Portions),
+	}
+}
+
+func newPortionInfos(portions []models.Portion) []PortionInfo {
+	var infos []PortionInfo
+	for _, p := range portions {
+		infos = append(infos, PortionInfo{Name: p.Name(), Grams: p.GramWeight})
 	}
+	return infos
+}
 
-	var foodItems []FoodItem
-	for _, f := range foods {
-		foodItems = append(foodItems, FoodItem{
-			ID:       f.ID,
-			Name:     f.Name,
-			Calories: f.Calories,
-			Proteins: f.Proteins,
-			Carbs:    f.Carbs,
-			Fats:     f.Fats,
-			Fiber:    f.Fiber,
-		})
+// foodQuantity returns the quantity of a food requested in grams, or as a
+// household amount converted with the food portions when it is set
+func foodQuantity(food *models.Food, grams float64, amount string) (float64, error) {
+	if amount != "" {
+		quantity, err := food.ParseQuantity(amount)
+		if err != nil {
+			return 0, invalidf("%v", err)
+		}
+		return quantity, nil
+	}
+	if grams <= 0 {
+		return 0, invalidf("quantity must be positive")
 	}
+	return grams, nil
+}
 
-	return Response{
-		Data: SearchFoodResponseData{Foods: foodItems},
+func matchesAll(food models.Food, filters []models.NutrientFilter) bool {
+	for _, filter := range filters {
+		if !filter.Matches(food) {
+			return false
+		}
 	}
+	return true
 }
 
-func (s *Server) handleAddFood(untypedData any) Response {
+func (s *Server) handleAddFood(ctx context.Context, untypedData any) Response {
 	data, ok := untypedData.(AddFoodData)
 	if !ok {
-		return Response{Error: fmt.Errorf("invalid request data")}
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
 	}
 
-	dailyLog := s.userDB.GetDailyLog(time.Now())
-	if data.MealIndex < 0 || data.MealIndex >= len(dailyLog.Meals) {
-		return Response{Error: fmt.Errorf("invalid meal index")}
+	meal, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
 	}
 
-	// Get food details from FDC
-	fdcId := data.FoodID // Assuming format "fdc_123"
-	food, err := s.foodProcessor.GetFoodDetails(fdcId)
+	food, err := s.knownFood(ctx, data.FoodID)
 	if err != nil {
-		return Response{Error: fmt.Errorf("failed to get food details: %v", err)}
+		return Response{Error: err}
 	}
 
-	dailyLog.Meals[data.MealIndex].AddFood(food, data.Quantity)
+	quantity, err := foodQuantity(food, data.Quantity, data.Amount)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal.AddFood(food, quantity)
 	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
 		return Response{Error: fmt.Errorf("failed to save food: %v", err)}
 	}
 
+	return Response{Data: FoodQuantityResponse{Quantity: quantity}}
+}
+
+func (s *Server) handleRemoveFood(untypedData any) Response {
+	data, ok := untypedData.(RemoveFoodData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if data.FoodIndex < 0 || data.FoodIndex >= len(meal.Foods) {
+		return Response{Error: notFoundf("invalid food index")}
+	}
+
+	meal.RemoveFood(data.FoodIndex)
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to remove food: %v", err)}
+	}
+
+	return Response{}
+}
+
+func (s *Server) handleUpdateFoodQuantity(untypedData any) Response {
+	data, ok := untypedData.(UpdateFoodQuantityData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if data.FoodIndex < 0 || data.FoodIndex >= len(meal.Foods) {
+		return Response{Error: notFoundf("invalid food index")}
+	}
+
+	item := &meal.Foods[data.FoodIndex]
+	quantity, err := foodQuantity(item.Food, data.Quantity, data.Amount)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	item.Quantity = quantity
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to update food: %v", err)}
+	}
+
+	return Response{Data: FoodQuantityResponse{Quantity: quantity}}
+}
+
+func (s *Server) handleMoveFood(untypedData any) Response {
+	data, ok := untypedData.(MoveFoodData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	from, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	to, err := getMeal(dailyLog, data.ToMealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if data.FoodIndex < 0 || data.FoodIndex >= len(from.Foods) {
+		return Response{Error: notFoundf("invalid food index")}
+	}
+
+	item := from.RemoveFood(data.FoodIndex)
+	to.AddFood(item.Food, item.Quantity)
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to move food: %v", err)}
+	}
+
 	return Response{}
 }
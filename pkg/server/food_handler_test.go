@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestSearchFoodPaging(t *testing.T) {
+	s, _ := newTestServer(t, &testSource{name: "test", foods: 5000})
+
+	tests := []struct {
+		name           string
+		path           string
+		wantStatus     int
+		wantFoods      int
+		wantTotalPages int
+	}{
+		{"first page", "/foods/search?q=milk", http.StatusOK, 10, 100},
+		{"last page", "/foods/search?q=milk&page=100", http.StatusOK, 10, 100},
+		{"past the results", "/foods/search?q=milk&page=101", http.StatusBadRequest, 0, 0},
+		{"largest pages", "/foods/search?q=milk&page=5&size=200", http.StatusOK, 200, 5},
+		{"past the results with large pages", "/foods/search?q=milk&page=6&size=200", http.StatusBadRequest, 0, 0},
+		{"huge page", "/foods/search?q=milk&page=4611686018427387904&size=4", http.StatusBadRequest, 0, 0},
+		{"filtered", "/foods/search?q=milk&page=100&filter=kcal%3C150", http.StatusOK, 10, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp SearchFoodResponseData
+			status := doJSON(t, s, http.MethodGet, tt.path, nil, &resp)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if len(resp.Foods) != tt.wantFoods || resp.TotalPages != tt.wantTotalPages {
+				t.Errorf("%d foods of %d pages, want %d foods of %d pages", len(resp.Foods), resp.TotalPages, tt.wantFoods, tt.wantTotalPages)
+			}
+		})
+	}
+}
+
+func TestAddFoodStored(t *testing.T) {
+	s, _ := newTestServer(t, &testSource{name: "test", foods: 1})
+	userID := createTestUser(t, s)
+	if resp := s.dispatch(context.Background(), ReqAddMeal, AddMealData{UserID: userID, Name: "Lunch"}); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+
+	// The source fails the requests of a canceled context
+	offline, cancel := context.WithCancel(context.Background())
+	cancel()
+	add := AddFoodData{UserID: userID, FoodID: "test_1", Quantity: 100}
+
+	if resp := s.dispatch(offline, ReqAddFood, add); KindOf(resp.Error) != ErrUpstream {
+		t.Errorf("adding a food never stored offline error = %v, want an upstream error", resp.Error)
+	}
+	if resp := s.dispatch(context.Background(), ReqAddFood, add); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+	if resp := s.dispatch(offline, ReqAddFood, add); resp.Error != nil {
+		t.Errorf("adding a stored food offline error = %v", resp.Error)
+	}
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strings"
+)
+
+// FoodSource is a provider of food data, such as the USDA FoodData Central
+// API. Food IDs are local to the source: the server prefixes them with the
+// source name, as in "fdc_171287". Sources sharing a name share their IDs, as
+// the local FDC catalog and the FDC API do.
+type FoodSource interface {
+	Name() string
+	SearchFoods(query models.FoodQuery) (models.SearchResult, error)
+	GetFoodDetails(id string) (*models.Food, error)
+}
+
+// BarcodeSource is implemented by the food sources that can find branded
+// products by barcode
+type BarcodeSource interface {
+	// LookupBarcode finds a product by GTIN, as normalized by
+	// models.NormalizeGTIN
+	LookupBarcode(gtin string) (*models.Food, error)
+}
+
+// ContextSource is implemented by the food sources whose requests can be
+// canceled, such as those reaching the network. The server gives them the
+// context of the request being handled, so that they stop when its client
+// goes away.
+type ContextSource interface {
+	SearchFoodsContext(ctx context.Context, query models.FoodQuery) (models.SearchResult, error)
+	GetFoodDetailsContext(ctx context.Context, id string) (*models.Food, error)
+}
+
+// ContextBarcodeSource is implemented by the barcode sources whose lookups
+// can be canceled
+type ContextBarcodeSource interface {
+	LookupBarcodeContext(ctx context.Context, gtin string) (*models.Food, error)
+}
+
+// CustomFoodStore is implemented by the food source of the foods created by
+// users. Its IDs are local to the source, as for searches.
+type CustomFoodStore interface {
+	CreateFood(food *models.Food) error
+	// UpdateFood replaces a food, failing with models.ErrFoodNotFound when
+	// there is none with its ID
+	UpdateFood(food *models.Food) error
+	ListFoods() ([]models.Food, error)
+}
+
+// RecipeStore is implemented by the food source of the recipes, whose foods
+// describe a dish per 100g
+type RecipeStore interface {
+	CreateRecipe(recipe *models.Recipe) error
+	// UpdateRecipe replaces a recipe, failing with models.ErrFoodNotFound
+	// when there is none with its ID
+	UpdateRecipe(recipe *models.Recipe) error
+	GetRecipe(id string) (*models.Recipe, error)
+	ListRecipes() ([]models.Recipe, error)
+}
+
+// foodIDSeparator separates the source name from the source's own food ID
+const foodIDSeparator = "_"
+
+// filterScanPages bounds the pages of models.MaxPageSize foods read from each
+// source by a filtered search: the sources cannot filter, so the filters apply
+// to the foods read
+const filterScanPages = 5
+
+// searchFoods searches every source and returns the page of the query from
+// their merged results, keeping only the foods matching the filters, with the
+// number of foods the filters left out. The merge takes, one food at a time, the best of the
+// next foods of each source in the query order, earlier sources winning ties,
+// so that every page is a slice of the same merged list. Foods found by
+// several sources, with the same ID or the same name and brand, are only kept
+// from the first one. It fails only when every source does.
+func (s *Server) searchFoods(ctx context.Context, query models.FoodQuery, filters []models.NutrientFilter) (models.SearchResult, int, error) {
+	var result models.SearchResult
+	if len(s.foodSources) == 0 {
+		return result, 0, errors.New("no food source available")
+	}
+
+	// The foods of the page come from the first foods of each source, and
+	// filtered searches read them all to count the matching foods
+	window := query.Page * query.PageSize
+	if len(filters) > 0 {
+		window = max(window, filterScanPages*models.MaxPageSize)
+	}
+	window = min(window, models.MaxResults)
+	var found []sourceResults
+	var errs []error
+	for _, source := range s.foodSources {
+		results, err := searchFirst(ctx, source, query, window)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", source.Name(), err))
+			continue
+		}
+		result.TotalHits += results.TotalHits
+		found = append(found, results)
+	}
+	if len(errs) == len(s.foodSources) {
+		return result, 0, errors.Join(errs...)
+	}
+
+	before := func(a, b models.Food) bool {
+		return normalizeFoodName(a.Name) < normalizeFoodName(b.Name)
+	}
+	if query.Sort != models.SortName {
+		terms := strings.Fields(normalizeFoodName(query.Text))
+		before = func(a, b models.Food) bool {
+			return matchScore(a.Name, terms) > matchScore(b.Name, terms)
+		}
+	}
+
+	var merged []models.Food
+	filtered := 0
+	seen := make(map[string]bool)
+	for len(merged) < window {
+		next, unknown := -1, false
+		for i, results := range found {
+			if len(results.Foods) == 0 {
+				// Past the foods read from a source with more, the order is unknown
+				unknown = unknown || results.more
+				continue
+			}
+			if next < 0 || before(results.Foods[0], found[next].Foods[0]) {
+				next = i
+			}
+		}
+		if next < 0 || unknown {
+			break
+		}
+
+		food := found[next].Foods[0]
+		found[next].Foods = found[next].Foods[1:]
+		food.ID = found[next].source.Name() + foodIDSeparator + food.ID
+		key := normalizeFoodName(food.Name + "|" + food.BrandOwner)
+		if seen[key] || seen[food.ID] {
+			result.TotalHits--
+			continue
+		}
+		seen[key], seen[food.ID] = true, true
+		if !matchesAll(food, filters) {
+			filtered++
+			continue
+		}
+		merged = append(merged, food)
+	}
+	if len(filters) > 0 {
+		result.TotalHits = len(merged)
+	}
+
+	if first := (query.Page - 1) * query.PageSize; first < len(merged) {
+		result.Foods = merged[first:min(len(merged), first+query.PageSize)]
+	}
+	return result, filtered, nil
+}
+
+// sourceResults are the first foods found by a source
+type sourceResults struct {
+	models.SearchResult
+	source FoodSource
+	// more tells whether the source found foods past those read
+	more bool
+}
+
+// searchFirst reads the first n foods found by a source, a page at a time
+func searchFirst(ctx context.Context, source FoodSource, query models.FoodQuery, n int) (sourceResults, error) {
+	results := sourceResults{source: source}
+	query.PageSize = min(n, models.MaxPageSize)
+	for query.Page = 1; len(results.Foods) < n; query.Page++ {
+		found, err := searchSource(ctx, source, query)
+		if err != nil {
+			return results, err
+		}
+		results.TotalHits = found.TotalHits
+		results.Foods = append(results.Foods, found.Foods...)
+		if len(found.Foods) < query.PageSize {
+			return results, nil
+		}
+	}
+	results.Foods = results.Foods[:n]
+	results.more = results.TotalHits > n
+	return results, nil
+}
+
+// getFoodDetails fetches a food from the sources its ID is prefixed with the
+// name of, in order, until one knows it
+func (s *Server) getFoodDetails(ctx context.Context, id string) (*models.Food, error) {
+	sourceName, sourceID, ok := strings.Cut(id, foodIDSeparator)
+	if !ok {
+		return nil, notFoundf("unknown food %q", id)
+	}
+
+	var notFound error
+	for _, source := range s.foodSources {
+		if source.Name() != sourceName {
+			continue
+		}
+
+		food, err := sourceFoodDetails(ctx, source, sourceID)
+		if errors.Is(err, models.ErrFoodNotFound) {
+			notFound = err
+			continue
+		}
+		if err != nil {
+			return nil, upstreamf("failed to get food details: %v", err)
+		}
+		food.ID = id
+		return food, nil
+	}
+	if notFound != nil {
+		return nil, notFoundf("unknown food %q: %v", id, notFound)
+	}
+	return nil, notFoundf("unknown food %q", id)
+}
+
+// knownFood returns a food stored along with the logs, or fetches it from
+// the sources when it was never stored. Foods the user already logged can
+// thus be used again offline, as from the recent foods.
+func (s *Server) knownFood(ctx context.Context, id string) (*models.Food, error) {
+	food, err := s.userDB.StoredFood(id)
+	if errors.Is(err, models.ErrFoodNotFound) {
+		return s.getFoodDetails(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load food: %v", err)
+	}
+	return food, nil
+}
+
+// lookupBarcode asks the sources supporting barcodes, in order, for the
+// product with a GTIN
+func (s *Server) lookupBarcode(ctx context.Context, gtin string) (*models.Food, error) {
+	var errs []error
+	for _, source := range s.foodSources {
+		barcodes, ok := source.(BarcodeSource)
+		if !ok {
+			continue
+		}
+
+		food, err := sourceBarcode(ctx, barcodes, gtin)
+		if errors.Is(err, models.ErrFoodNotFound) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", source.Name(), err))
+			continue
+		}
+		food.ID = source.Name() + foodIDSeparator + food.ID
+		return food, nil
+	}
+
+	if len(errs) > 0 {
+		return nil, upstreamf("barcode lookup failed: %v", errors.Join(errs...))
+	}
+	return nil, notFoundf("no product found with barcode %s", gtin)
+}
+
+// searchSource searches a source, with the context when it supports one
+func searchSource(ctx context.Context, source FoodSource, query models.FoodQuery) (models.SearchResult, error) {
+	if source, ok := source.(ContextSource); ok {
+		return source.SearchFoodsContext(ctx, query)
+	}
+	return source.SearchFoods(query)
+}
+
+// sourceFoodDetails gets a food from a source, with the context when it
+// supports one
+func sourceFoodDetails(ctx context.Context, source FoodSource, id string) (*models.Food, error) {
+	if source, ok := source.(ContextSource); ok {
+		return source.GetFoodDetailsContext(ctx, id)
+	}
+	return source.GetFoodDetails(id)
+}
+
+// sourceBarcode looks a barcode up in a source, with the context when it
+// supports one
+func sourceBarcode(ctx context.Context, source BarcodeSource, gtin string) (*models.Food, error) {
+	if source, ok := source.(ContextBarcodeSource); ok {
+		return source.LookupBarcodeContext(ctx, gtin)
+	}
+	return source.LookupBarcode(gtin)
+}
+
+// customFoods returns the food source storing custom foods
+func (s *Server) customFoods() (FoodSource, CustomFoodStore, error) {
+	source, store, ok := sourceWith[CustomFoodStore](s.foodSources)
+	if !ok {
+		return nil, nil, invalidf("custom foods are not available")
+	}
+	return source, store, nil
+}
+
+// recipes returns the food source storing recipes
+func (s *Server) recipes() (FoodSource, RecipeStore, error) {
+	source, store, ok := sourceWith[RecipeStore](s.foodSources)
+	if !ok {
+		return nil, nil, invalidf("recipes are not available")
+	}
+	return source, store, nil
+}
+
+// sourceWith returns the first food source implementing T
+func sourceWith[T any](sources []FoodSource) (FoodSource, T, bool) {
+	for _, source := range sources {
+		if store, ok := source.(T); ok {
+			return source, store, true
+		}
+	}
+	var none T
+	return nil, none, false
+}
+
+func normalizeFoodName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// matchScore ranks how well a food name matches the search terms: names
+// starting with the query come first, then names containing more of the
+// terms, shorter names breaking ties
+func matchScore(name string, terms []string) float64 {
+	name = normalizeFoodName(name)
+	var score float64
+	if len(terms) > 0 && strings.HasPrefix(name, terms[0]) {
+		score += 10
+	}
+	for _, term := range terms {
+		if strings.Contains(name, term) {
+			score += 2
+		}
+	}
+	return score - float64(len(name))/100
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"testing"
+)
+
+// testSource is a food source of numbered foods named after the query,
+// failing with the error of its context once done
+type testSource struct {
+	name  string
+	foods int
+}
+
+func (s *testSource) Name() string {
+	return s.name
+}
+
+func (s *testSource) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	return s.SearchFoodsContext(context.Background(), query)
+}
+
+func (s *testSource) SearchFoodsContext(ctx context.Context, query models.FoodQuery) (models.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return models.SearchResult{}, err
+	}
+	result := models.SearchResult{TotalHits: s.foods}
+	for i := (query.Page - 1) * query.PageSize; i < min(s.foods, query.Page*query.PageSize); i++ {
+		result.Foods = append(result.Foods, models.Food{ID: fmt.Sprint(i + 1), Name: fmt.Sprintf("%s %d", query.Text, i+1)})
+	}
+	return result, nil
+}
+
+func (s *testSource) GetFoodDetails(id string) (*models.Food, error) {
+	return s.GetFoodDetailsContext(context.Background(), id)
+}
+
+func (s *testSource) GetFoodDetailsContext(ctx context.Context, id string) (*models.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &models.Food{ID: id, Name: "Food " + id, Calories: 100}, nil
+}
+
+func (s *testSource) LookupBarcode(gtin string) (*models.Food, error) {
+	return s.LookupBarcodeContext(context.Background(), gtin)
+}
+
+func (s *testSource) LookupBarcodeContext(ctx context.Context, gtin string) (*models.Food, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &models.Food{ID: "1", Name: "Product", GTIN: gtin}, nil
+}
+
+func TestDispatchPassesContext(t *testing.T) {
+	s, _ := newTestServer(t, &testSource{name: "test", foods: 3})
+	userID := createTestUser(t, s)
+	if resp := s.dispatch(context.Background(), ReqAddMeal, AddMealData{UserID: userID, Name: "Lunch"}); resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		reqType string
+		data    any
+	}{
+		{ReqSearchFood, SearchFoodData{UserID: userID, Query: "milk"}},
+		{ReqLookupBarcode, LookupBarcodeData{UserID: userID, Code: "012345678905"}},
+		{ReqAddFood, AddFoodData{UserID: userID, FoodID: "test_1", Quantity: 100}},
+		{ReqStarFood, StarFoodData{UserID: userID, FoodID: "test_2", Starred: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reqType, func(t *testing.T) {
+			if resp := s.dispatch(canceled, tt.reqType, tt.data); KindOf(resp.Error) != ErrUpstream {
+				t.Errorf("canceled request error = %v, want an upstream error", resp.Error)
+			}
+			if resp := s.dispatch(context.Background(), tt.reqType, tt.data); resp.Error != nil {
+				t.Errorf("request error = %v", resp.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"nutritionapp/pkg/models"
+)
+
+// Bounds of the number of foods listed by the food usage requests
+const (
+	defaultUsageLimit = 10
+	maxUsageLimit     = 100
+)
+
+// handleFoodUsage lists the foods a user logs, as listed by list
+func (s *Server) handleFoodUsage(untypedData any, list func(userID int64, limit int) ([]models.FoodUsage, error)) Response {
+	data, ok := untypedData.(FoodUsageData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if _, err := s.getUser(data.UserID); err != nil {
+		return Response{Error: err}
+	}
+
+	if data.Limit < 0 || data.Limit > maxUsageLimit {
+		return Response{Error: invalidf("limit must be between 1 and %d", maxUsageLimit)}
+	}
+	if data.Limit == 0 {
+		data.Limit = defaultUsageLimit
+	}
+
+	usages, err := list(data.UserID, data.Limit)
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to list foods: %v", err)}
+	}
+
+	resp := FoodUsageResponse{Foods: make([]FoodUsageInfo, 0, len(usages))}
+	for _, usage := range usages {
+		info := FoodUsageInfo{FoodItem: newFoodItem(usage.Food), Uses: usage.Uses, Starred: usage.Starred}
+		if !usage.LastUsed.IsZero() {
+			info.LastUsed = usage.LastUsed.Format(DateFormat)
+		}
+		resp.Foods = append(resp.Foods, info)
+	}
+	return Response{Data: resp}
+}
+
+func (s *Server) handleStarFood(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(StarFoodData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if _, err := s.getUser(data.UserID); err != nil {
+		return Response{Error: err}
+	}
+
+	// Unstarring needs no details, and works for foods no source knows
+	// anymore
+	food := &models.Food{ID: data.FoodID}
+	if data.Starred {
+		known, err := s.knownFood(ctx, data.FoodID)
+		if err != nil {
+			return Response{Error: err}
+		}
+		food = known
+	}
+
+	if err := s.userDB.SetFavorite(data.UserID, food, data.Starred); err != nil {
+		return Response{Error: fmt.Errorf("failed to save favorite: %v", err)}
+	}
+	return Response{}
+}
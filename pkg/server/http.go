@@ -0,0 +1,421 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// ErrorResponse is the JSON body returned by the HTTP API when a request fails
+type ErrorResponse struct {
+	Error string
+}
+
+// requestBuilder turns an HTTP request into the data of a server request
+type requestBuilder func(r *http.Request) (any, error)
+
+// HTTPHandler exposes the server requests as a JSON REST API.
+// Meal and food indexes are zero-based, as in MealInfo, and every route
+// working on a daily log accepts an optional ?date=YYYY-MM-DD.
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	s.route(mux, "GET /users", ReqListUsers, func(r *http.Request) (any, error) {
+		return nil, nil
+	})
+	s.route(mux, "POST /users", ReqCreateProfile, func(r *http.Request) (any, error) {
+		var data CreateProfileData
+		err := decodeBody(r, &data)
+		return data, err
+	})
+	s.route(mux, "GET /users/{user}", ReqGetProfile, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		return GetProfileData{UserID: userID}, err
+	})
+	s.route(mux, "PUT /users/{user}", ReqUpdateProfile, func(r *http.Request) (any, error) {
+		var data UpdateProfileData
+		if err := decodeBody(r, &data.CreateProfileData); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID = userID
+		return data, err
+	})
+
+	s.route(mux, "GET /users/{user}/meals", ReqListMeals, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		return ListMealsData{UserID: userID, Date: r.URL.Query().Get("date")}, err
+	})
+	s.route(mux, "POST /users/{user}/meals", ReqAddMeal, func(r *http.Request) (any, error) {
+		var data AddMealData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID, data.Date = userID, r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "PATCH /users/{user}/meals/{meal}", ReqRenameMeal, func(r *http.Request) (any, error) {
+		var data RenameMealData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		err := parsePath(r, &data.UserID, &data.MealIndex, nil)
+		data.Date = r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "DELETE /users/{user}/meals/{meal}", ReqDeleteMeal, func(r *http.Request) (any, error) {
+		data := DeleteMealData{Date: r.URL.Query().Get("date")}
+		err := parsePath(r, &data.UserID, &data.MealIndex, nil)
+		return data, err
+	})
+
+	s.route(mux, "POST /users/{user}/meals/{meal}/foods", ReqAddFood, func(r *http.Request) (any, error) {
+		var data AddFoodData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		err := parsePath(r, &data.UserID, &data.MealIndex, nil)
+		data.Date = r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "PATCH /users/{user}/meals/{meal}/foods/{food}", ReqUpdateFoodQuantity, func(r *http.Request) (any, error) {
+		var data UpdateFoodQuantityData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		err := parsePath(r, &data.UserID, &data.MealIndex, &data.FoodIndex)
+		data.Date = r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "DELETE /users/{user}/meals/{meal}/foods/{food}", ReqRemoveFood, func(r *http.Request) (any, error) {
+		data := RemoveFoodData{Date: r.URL.Query().Get("date")}
+		err := parsePath(r, &data.UserID, &data.MealIndex, &data.FoodIndex)
+		return data, err
+	})
+	s.route(mux, "POST /users/{user}/meals/{meal}/foods/{food}/move", ReqMoveFood, func(r *http.Request) (any, error) {
+		var data MoveFoodData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		err := parsePath(r, &data.UserID, &data.MealIndex, &data.FoodIndex)
+		data.Date = r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "POST /users/{user}/meals/copy", ReqCopyMeals, func(r *http.Request) (any, error) {
+		var data CopyMealsData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID, data.Date = userID, r.URL.Query().Get("date")
+		return data, err
+	})
+
+	s.route(mux, "GET /users/{user}/templates", ReqListMealTemplates, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		return ListMealTemplatesData{UserID: userID}, err
+	})
+	s.route(mux, "POST /users/{user}/templates", ReqSaveMealTemplate, func(r *http.Request) (any, error) {
+		var data SaveMealTemplateData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID, data.Date = userID, r.URL.Query().Get("date")
+		return data, err
+	})
+	s.route(mux, "POST /users/{user}/templates/{template}/apply", ReqApplyMealTemplate, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		if err != nil {
+			return nil, err
+		}
+		templateID, err := pathID(r, "template")
+		return ApplyMealTemplateData{UserID: userID, Date: r.URL.Query().Get("date"), TemplateID: templateID}, err
+	})
+
+	s.route(mux, "GET /users/{user}/report", ReqGetReport, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		query := r.URL.Query()
+		return GetReportData{UserID: userID, Date: query.Get("date"), Nutrients: query["nutrient"]}, err
+	})
+	s.route(mux, "GET /users/{user}/report/period", ReqGetPeriodReport, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		query := r.URL.Query()
+		return PeriodReportData{UserID: userID, From: query.Get("from"), To: query.Get("to"), Nutrients: query["nutrient"]}, err
+	})
+
+	s.route(mux, "GET /users/{user}/weights", ReqWeightHistory, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		if err != nil {
+			return nil, err
+		}
+		data := WeightHistoryData{UserID: userID}
+		if days := r.URL.Query().Get("days"); days != "" {
+			if data.Days, err = strconv.Atoi(days); err != nil {
+				return nil, invalidf("invalid number of days %q", days)
+			}
+		}
+		return data, nil
+	})
+	s.route(mux, "POST /users/{user}/weights", ReqLogWeight, func(r *http.Request) (any, error) {
+		var data LogWeightData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID, data.Date = userID, r.URL.Query().Get("date")
+		return data, err
+	})
+
+	s.route(mux, "GET /users/{user}/quick-add", ReqParseFoodLine, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		query := r.URL.Query()
+		return FoodLineData{UserID: userID, Date: query.Get("date"), Text: query.Get("text")}, err
+	})
+	s.route(mux, "POST /users/{user}/quick-add", ReqAddFoodLine, func(r *http.Request) (any, error) {
+		var data AddFoodLineData
+		if err := decodeBody(r, &data); err != nil {
+			return nil, err
+		}
+		userID, err := pathID(r, "user")
+		data.UserID, data.Date = userID, r.URL.Query().Get("date")
+		return data, err
+	})
+
+	s.route(mux, "GET /users/{user}/foods/recent", ReqRecentFoods, func(r *http.Request) (any, error) {
+		return foodUsageData(r)
+	})
+	s.route(mux, "GET /users/{user}/foods/favorites", ReqFavoriteFoods, func(r *http.Request) (any, error) {
+		return foodUsageData(r)
+	})
+	s.route(mux, "PUT /users/{user}/foods/favorites/{food}", ReqStarFood, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		return StarFoodData{UserID: userID, FoodID: r.PathValue("food"), Starred: true}, err
+	})
+	s.route(mux, "DELETE /users/{user}/foods/favorites/{food}", ReqStarFood, func(r *http.Request) (any, error) {
+		userID, err := pathID(r, "user")
+		return StarFoodData{UserID: userID, FoodID: r.PathValue("food")}, err
+	})
+
+	s.route(mux, "GET /foods/search", ReqSearchFood, func(r *http.Request) (any, error) {
+		query := r.URL.Query()
+		data := SearchFoodData{Query: query.Get("q"), DataTypes: query["type"], Sort: query.Get("sort"), Filters: query["filter"]}
+		var err error
+		if page := query.Get("page"); page != "" {
+			if data.Page, err = strconv.Atoi(page); err != nil {
+				return nil, invalidf("invalid page %q", page)
+			}
+		}
+		if size := query.Get("size"); size != "" {
+			if data.PageSize, err = strconv.Atoi(size); err != nil {
+				return nil, invalidf("invalid page size %q", size)
+			}
+		}
+		return data, nil
+	})
+
+	s.route(mux, "GET /foods/barcode/{code}", ReqLookupBarcode, func(r *http.Request) (any, error) {
+		return LookupBarcodeData{Code: r.PathValue("code")}, nil
+	})
+	s.route(mux, "GET /foods/custom", ReqListCustomFoods, func(r *http.Request) (any, error) {
+		return ListCustomFoodsData{}, nil
+	})
+	s.route(mux, "POST /foods/custom", ReqCreateCustomFood, func(r *http.Request) (any, error) {
+		var data CustomFoodData
+		err := decodeBody(r, &data)
+		return data, err
+	})
+	s.route(mux, "PUT /foods/{food}", ReqUpdateCustomFood, func(r *http.Request) (any, error) {
+		var data CustomFoodData
+		err := decodeBody(r, &data)
+		data.ID = r.PathValue("food")
+		return data, err
+	})
+
+	s.route(mux, "GET /recipes", ReqListRecipes, func(r *http.Request) (any, error) {
+		return ListRecipesData{}, nil
+	})
+	s.route(mux, "POST /recipes", ReqCreateRecipe, func(r *http.Request) (any, error) {
+		var data RecipeData
+		err := decodeBody(r, &data)
+		return data, err
+	})
+	s.route(mux, "PUT /recipes/{recipe}", ReqUpdateRecipe, func(r *http.Request) (any, error) {
+		var data RecipeData
+		err := decodeBody(r, &data)
+		data.ID = r.PathValue("recipe")
+		return data, err
+	})
+
+	// Generic endpoint taking any request type with its payload as the body,
+	// used by remote clients
+	mux.HandleFunc("POST /rpc/{type}", func(w http.ResponseWriter, r *http.Request) {
+		reqType := r.PathValue("type")
+		newPayload, ok := requestPayloads[reqType]
+		if !ok {
+			writeError(w, invalidf("unknown request type: %s", reqType))
+			return
+		}
+
+		var data any
+		if newPayload != nil {
+			p := newPayload()
+			if err := decodeBody(r, p); err != nil {
+				writeError(w, err)
+				return
+			}
+			data = reflect.ValueOf(p).Elem().Interface()
+		}
+
+		resp := s.dispatch(r.Context(), reqType, data)
+		if resp.Error != nil {
+			writeError(w, resp.Error)
+			return
+		}
+		if resp.Data == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp.Data)
+	})
+
+	return mux
+}
+
+// route registers a pattern dispatching to the handler of reqType
+func (s *Server) route(mux *http.ServeMux, pattern string, reqType string, build requestBuilder) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		data, err := build(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resp := s.dispatch(r.Context(), reqType, data)
+		if resp.Error != nil {
+			writeError(w, resp.Error)
+			return
+		}
+
+		if resp.Data == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, resp.Data)
+	})
+}
+
+// StatusCode maps a request error to the matching HTTP status
+func StatusCode(err error) int {
+	switch KindOf(err) {
+	case ErrInvalid:
+		return http.StatusBadRequest
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrUpstream:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// StatusError rebuilds the request error a response status was mapped from,
+// so that the API clients can tell its kind
+func StatusError(status int, message string) error {
+	kind := ErrInternal
+	switch status {
+	case http.StatusBadRequest:
+		kind = ErrInvalid
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	case http.StatusBadGateway:
+		kind = ErrUpstream
+	}
+	return &Error{Kind: kind, Err: errors.New(message)}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, StatusCode(err), ErrorResponse{Error: err.Error()})
+}
+
+// writeJSON encodes the body before writing the status, so that a body that
+// cannot be encoded, such as one holding NaN, fails with an error response
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(ErrorResponse{Error: "failed to encode response"})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// decodeBody decodes the JSON body of a request, an empty body leaving v unchanged
+func decodeBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return invalidf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func pathID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		return 0, invalidf("invalid %s ID %q", name, r.PathValue(name))
+	}
+	return id, nil
+}
+
+func pathIndex(r *http.Request, name string) (int, error) {
+	index, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, invalidf("invalid %s index %q", name, r.PathValue(name))
+	}
+	return index, nil
+}
+
+// parsePath reads the user, meal and food path values; foodIndex is nil for
+// routes without a food
+func parsePath(r *http.Request, userID *int64, mealIndex *int, foodIndex *int) error {
+	var err error
+	if *userID, err = pathID(r, "user"); err != nil {
+		return err
+	}
+	if *mealIndex, err = pathIndex(r, "meal"); err != nil {
+		return err
+	}
+	if foodIndex != nil {
+		*foodIndex, err = pathIndex(r, "food")
+	}
+	return err
+}
+
+// foodUsageData reads the user and the optional ?limit=N of the food usage
+// routes
+func foodUsageData(r *http.Request) (any, error) {
+	userID, err := pathID(r, "user")
+	if err != nil {
+		return nil, err
+	}
+	data := FoodUsageData{UserID: userID}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		if data.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, invalidf("invalid limit %q", limit)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"nutritionapp/pkg/models"
+	"strconv"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       any
+		wantStatus int
+		wantError  bool
+	}{
+		{"encodable", http.StatusCreated, NutritionValues{Calories: 100}, http.StatusCreated, false},
+		{"NaN", http.StatusOK, NutritionValues{Calories: math.NaN()}, http.StatusInternalServerError, true},
+		{"infinity", http.StatusOK, NutritionValues{Proteins: math.Inf(1)}, http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeJSON(rec, tt.status, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var resp ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid body %q: %v", rec.Body.String(), err)
+			}
+			if (resp.Error != "") != tt.wantError {
+				t.Errorf("error = %q, want error %v", resp.Error, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestGetProfileUnknownMeasurements(t *testing.T) {
+	s, userDB := newTestServer(t)
+	// Profiles created before measurements were checked, or as the
+	// placeholder of migrated logs, may not know them
+	user := &models.User{FirstName: "Default", LastName: "User", Goal: models.Goal{Type: models.GoalMaintenance}}
+	if err := userDB.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	var profile ProfileResponseData
+	status := doJSON(t, s, http.MethodGet, "/users/"+strconv.FormatInt(user.ID, 10), nil, &profile)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if profile.FirstName != "Default" || profile.BMI != 0 || profile.BMR != 0 || profile.TDEE != 0 {
+		t.Errorf("profile = %+v, want unknown estimates", profile)
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"nutritionapp/pkg/models"
+	"slices"
+	"strings"
+)
+
+func (s *Server) handleCopyMeals(untypedData any) Response {
+	data, ok := untypedData.(CopyMealsData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	if data.From == "" {
+		return Response{Error: invalidf("the date to copy from is required")}
+	}
+	from, err := s.getDailyLog(data.UserID, data.From)
+	if err != nil {
+		return Response{Error: err}
+	}
+	to, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+	fromDate := from.Date.Format(DateFormat)
+	if fromDate == to.Date.Format(DateFormat) {
+		return Response{Error: invalidf("cannot copy meals onto the day they are from")}
+	}
+
+	name := strings.TrimSpace(data.Meal)
+	var copies []*models.Meal
+	for _, meal := range from.Meals {
+		if name == "" || strings.EqualFold(meal.Name, name) {
+			copies = append(copies, meal.CopyTo(to.Date))
+		}
+	}
+	if len(copies) == 0 {
+		if name != "" {
+			return Response{Error: notFoundf("no %s meal on %s", name, fromDate)}
+		}
+		return Response{Error: notFoundf("no meals on %s", fromDate)}
+	}
+
+	resp := CopyMealsResponse{Meals: len(copies)}
+	for _, meal := range copies {
+		resp.Foods += len(meal.Foods)
+	}
+	to.Meals = append(to.Meals, copies...)
+	if err := s.userDB.SaveDailyLog(to); err != nil {
+		return Response{Error: fmt.Errorf("failed to copy meals: %v", err)}
+	}
+	return Response{Data: resp}
+}
+
+func (s *Server) handleSaveMealTemplate(untypedData any) Response {
+	data, ok := untypedData.(SaveMealTemplateData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+	if len(meal.Foods) == 0 {
+		return Response{Error: invalidf("meal %s has no food to save", meal.Name)}
+	}
+
+	template := &models.MealTemplate{Name: strings.TrimSpace(data.Name), Meal: *meal}
+	if template.Name == "" {
+		template.Name = meal.Name
+	}
+	if err := s.userDB.SaveMealTemplate(data.UserID, template); err != nil {
+		return Response{Error: fmt.Errorf("failed to save meal template: %v", err)}
+	}
+
+	return Response{Data: MealTemplateResponse{Template: newMealTemplateInfo(*template)}}
+}
+
+func (s *Server) handleListMealTemplates(untypedData any) Response {
+	data, ok := untypedData.(ListMealTemplatesData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if _, err := s.getUser(data.UserID); err != nil {
+		return Response{Error: err}
+	}
+
+	templates, err := s.userDB.ListMealTemplates(data.UserID)
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to list meal templates: %v", err)}
+	}
+
+	infos := make([]MealTemplateInfo, 0, len(templates))
+	for _, template := range templates {
+		infos = append(infos, newMealTemplateInfo(template))
+	}
+	return Response{Data: MealTemplateListResponse{Templates: infos}}
+}
+
+func (s *Server) handleApplyMealTemplate(untypedData any) Response {
+	data, ok := untypedData.(ApplyMealTemplateData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	templates, err := s.userDB.ListMealTemplates(data.UserID)
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to load meal templates: %v", err)}
+	}
+	index := slices.IndexFunc(templates, func(t models.MealTemplate) bool {
+		return t.ID == data.TemplateID
+	})
+	if index < 0 {
+		return Response{Error: notFoundf("unknown meal template %d", data.TemplateID)}
+	}
+
+	meal := templates[index].Instantiate(mealTime(dailyLog.Date))
+	dailyLog.Meals = append(dailyLog.Meals, meal)
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to save meal: %v", err)}
+	}
+
+	return Response{Data: MealInfo{
+		Index:     len(dailyLog.Meals) - 1,
+		Name:      meal.Name,
+		Time:      meal.Time.Format("15:04"),
+		FoodItems: newFoodItemInfos(meal),
+	}}
+}
+
+// newMealTemplateInfo describes a meal template with its foods
+func newMealTemplateInfo(template models.MealTemplate) MealTemplateInfo {
+	return MealTemplateInfo{
+		ID:        template.ID,
+		Name:      template.Name,
+		MealName:  template.Meal.Name,
+		FoodItems: newFoodItemInfos(&template.Meal),
+		Calories:  template.Meal.CalculateTotals().Calories,
+	}
+}
@@ -9,13 +9,18 @@ import (
 func (s *Server) handleAddMeal(untypedData any) Response {
 	data, ok := untypedData.(AddMealData)
 	if !ok {
-		return Response{Error: fmt.Errorf("invalid request data")}
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
 	}
 
-	dailyLog := s.userDB.GetDailyLog(time.Now())
 	meal := models.Meal{
 		Name:  data.Name,
-		Time:  time.Now(),
+		Time:  mealTime(dailyLog.Date),
 		Foods: make([]models.FoodQuantity, 0),
 	}
 
@@ -27,38 +32,108 @@ func (s *Server) handleAddMeal(untypedData any) Response {
 	return Response{}
 }
 
+// mealTime returns the time of a meal added now to a day
+func mealTime(day time.Time) time.Time {
+	now := time.Now()
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, time.Local)
+}
+
 func (s *Server) handleListMeals(untypedData any) Response {
-	dailyLog := s.userDB.GetDailyLog(time.Now())
-	if dailyLog == nil {
-		return Response{Error: fmt.Errorf("no meals found")}
+	data, ok := untypedData.(ListMealsData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
 	}
 
 	var meals []MealInfo
 	for i, meal := range dailyLog.Meals {
-		var foodItems []FoodItemInfo
-		for _, food := range meal.Foods {
-			foodItems = append(foodItems, FoodItemInfo{
-				Name:     food.Food.Name,
-				Quantity: food.Quantity,
-				Calories: food.Food.Calories,
-				Proteins: food.Food.Proteins,
-				Carbs:    food.Food.Carbs,
-				Fats:     food.Food.Fats,
-				Fiber:    food.Food.Fiber,
-			})
-		}
-
 		meals = append(meals, MealInfo{
 			Index:     i,
 			Name:      meal.Name,
 			Time:      meal.Time.Format("15:04"),
-			FoodItems: foodItems,
+			FoodItems: newFoodItemInfos(meal),
 		})
 	}
 
 	return Response{
 		Data: MealListResponse{
+			Date:  dailyLog.Date.Format(DateFormat),
 			Meals: meals,
 		},
 	}
 }
+
+func (s *Server) handleDeleteMeal(untypedData any) Response {
+	data, ok := untypedData.(DeleteMealData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if _, err := getMeal(dailyLog, data.MealIndex); err != nil {
+		return Response{Error: err}
+	}
+
+	dailyLog.RemoveMeal(data.MealIndex)
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to delete meal: %v", err)}
+	}
+
+	return Response{}
+}
+
+func (s *Server) handleRenameMeal(untypedData any) Response {
+	data, ok := untypedData.(RenameMealData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	defer s.lockUser(data.UserID)()
+
+	if data.Name == "" {
+		return Response{Error: invalidf("meal name cannot be empty")}
+	}
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal, err := getMeal(dailyLog, data.MealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	meal.Name = data.Name
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to rename meal: %v", err)}
+	}
+
+	return Response{}
+}
+
+// newFoodItemInfos describes the food items of a meal, in order
+func newFoodItemInfos(meal *models.Meal) []FoodItemInfo {
+	var foodItems []FoodItemInfo
+	for _, food := range meal.Foods {
+		foodItems = append(foodItems, FoodItemInfo{
+			Name:     food.Food.Name,
+			Quantity: food.Quantity,
+			Calories: food.Food.Calories,
+			Proteins: food.Food.Proteins,
+			Carbs:    food.Food.Carbs,
+			Fats:     food.Food.Fats,
+			Fiber:    food.Food.Fiber,
+			Portions: newPortionInfos(food.Food.Portions),
+		})
+	}
+	return foodItems
+}
@@ -3,60 +3,122 @@ package server
 import (
 	"fmt"
 	"nutritionapp/pkg/models"
+	"strings"
+	"time"
 )
 
+// getUser looks up the user a request refers to, with the measurements of
+// their latest weigh-in
+func (s *Server) getUser(userID int64) (*models.User, error) {
+	user := s.userDB.GetUser(userID)
+	if user == nil {
+		return nil, notFoundf("no profile exists")
+	}
+
+	if latest := s.userDB.GetLatestWeighIn(userID); latest != nil {
+		user.ApplyWeighIn(*latest)
+	}
+	return user, nil
+}
+
 func (s *Server) handleCreateProfile(untypedData any) Response {
 	data, ok := untypedData.(CreateProfileData)
 	if !ok {
-		return Response{Error: fmt.Errorf("invalid request data")}
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if err := validateMeasurements(data); err != nil {
+		return Response{Error: err}
+	}
+
+	activityLevel, err := parseActivityLevel(data.ActivityLevel)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	goal, err := parseGoal(data)
+	if err != nil {
+		return Response{Error: err}
 	}
 
 	user := &models.User{
-		FirstName: data.FirstName,
-		LastName:  data.LastName,
-		Age:       data.Age,
-		Weight:    data.Weight,
-		Height:    data.Height,
-		Gender:    data.Gender,
-		Goal:      data.Goal,
+		FirstName:     data.FirstName,
+		LastName:      data.LastName,
+		Age:           data.Age,
+		Weight:        data.Weight,
+		Height:        data.Height,
+		Gender:        data.Gender,
+		Goal:          goal,
+		ActivityLevel: activityLevel,
 	}
 
 	if err := s.userDB.SaveUser(user); err != nil {
 		return Response{Error: fmt.Errorf("failed to save user: %v", err)}
 	}
 
-	return Response{}
+	// The profile weight starts the weight history
+	weighIn := &models.WeighIn{Date: time.Now(), Weight: user.Weight}
+	if err := s.userDB.SaveWeighIn(user.ID, weighIn); err != nil {
+		return Response{Error: fmt.Errorf("failed to save weight: %v", err)}
+	}
+
+	return Response{
+		Data: newProfileResponse(user),
+	}
 }
 
 func (s *Server) handleGetProfile(untypedData any) Response {
-	user := s.userDB.GetUser()
-	if user == nil {
-		return Response{Error: fmt.Errorf("no profile exists")}
+	data, ok := untypedData.(GetProfileData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	user, err := s.getUser(data.UserID)
+	if err != nil {
+		return Response{Error: err}
 	}
 
 	return Response{
-		Data: ProfileResponseData{
-			FirstName:   user.FirstName,
-			LastName:    user.LastName,
-			Age:         user.Age,
-			Weight:      user.Weight,
-			Height:      user.Height,
-			Gender:      user.Gender,
-			Goal:        user.Goal,
-			BMI:         user.CalculateBMI(),
-			BodyFatPerc: user.EstimateBodyFat(),
-		},
+		Data: newProfileResponse(user),
 	}
 }
+
 func (s *Server) handleUpdateProfile(untypedData any) Response {
 	data, ok := untypedData.(UpdateProfileData)
 	if !ok {
-		return Response{Error: fmt.Errorf("invalid request data")}
+		return Response{Error: invalidf("invalid request data")}
 	}
+	defer s.lockUser(data.UserID)()
 
-	user := s.userDB.GetUser()
-	if user == nil {
-		return Response{Error: fmt.Errorf("no profile exists")}
+	user, err := s.getUser(data.UserID)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if err := validateMeasurements(data.CreateProfileData); err != nil {
+		return Response{Error: err}
+	}
+
+	activityLevel, err := parseActivityLevel(data.ActivityLevel)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	goal, err := parseGoal(data.CreateProfileData)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	// A new weight is recorded in the history, keeping the other
+	// measurements if today's weigh-in already has some
+	if data.Weight != user.Weight {
+		weighIn := &models.WeighIn{Date: time.Now(), Weight: data.Weight}
+		if latest := s.userDB.GetLatestWeighIn(user.ID); latest != nil && latest.Date.Format(DateFormat) == weighIn.Date.Format(DateFormat) {
+			weighIn.Waist, weighIn.Hip, weighIn.Neck, weighIn.BodyFat = latest.Waist, latest.Hip, latest.Neck, latest.BodyFat
+		}
+		if err := s.userDB.SaveWeighIn(user.ID, weighIn); err != nil {
+			return Response{Error: fmt.Errorf("failed to save weight: %v", err)}
+		}
 	}
 
 	user.FirstName = data.FirstName
@@ -65,23 +127,126 @@ func (s *Server) handleUpdateProfile(untypedData any) Response {
 	user.Weight = data.Weight
 	user.Height = data.Height
 	user.Gender = data.Gender
-	user.Goal = data.Goal
+	user.Goal = goal
+	user.ActivityLevel = activityLevel
 
 	if err := s.userDB.SaveUser(user); err != nil {
 		return Response{Error: fmt.Errorf("failed to update user: %v", err)}
 	}
 
 	return Response{
-		Data: ProfileResponseData{
-			FirstName:   user.FirstName,
-			LastName:    user.LastName,
-			Age:         user.Age,
-			Weight:      user.Weight,
-			Height:      user.Height,
-			Gender:      user.Gender,
-			Goal:        user.Goal,
-			BMI:         user.CalculateBMI(),
-			BodyFatPerc: user.EstimateBodyFat(),
-		},
+		Data: newProfileResponse(user),
+	}
+}
+
+func (s *Server) handleListUsers(untypedData any) Response {
+	users, err := s.userDB.ListUsers()
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to list users: %v", err)}
+	}
+
+	var infos []UserInfo
+	for _, user := range users {
+		infos = append(infos, UserInfo{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+		})
+	}
+
+	return Response{
+		Data: UserListResponse{Users: infos},
+	}
+}
+
+// Bounds of the age of a profile, in years
+const (
+	minAge = 1
+	maxAge = 120
+)
+
+// validateMeasurements checks the age, weight and height of a profile, which
+// its estimates and targets are derived from
+func validateMeasurements(data CreateProfileData) error {
+	if data.Age < minAge || data.Age > maxAge {
+		return invalidf("age must be between %d and %d", minAge, maxAge)
+	}
+	if data.Weight <= 0 {
+		return invalidf("weight must be positive")
+	}
+	if data.Height <= 0 {
+		return invalidf("height must be positive")
+	}
+	return nil
+}
+
+// parseActivityLevel validates an activity level, defaulting to sedentary
+func parseActivityLevel(level string) (models.ActivityLevel, error) {
+	if level == "" {
+		return models.ActivitySedentary, nil
+	}
+
+	activityLevel := models.ActivityLevel(level)
+	if activityLevel.Factor() == 0 {
+		return "", invalidf("invalid activity level %q", level)
+	}
+	return activityLevel, nil
+}
+
+// parseGoal validates the goal of a profile. Free-text goals such as
+// "weight loss" are accepted for the matching goal type.
+func parseGoal(data CreateProfileData) (models.Goal, error) {
+	goalType := models.GoalMaintenance
+	if data.Goal != "" {
+		goalType = models.GoalType(strings.ReplaceAll(strings.ToLower(strings.TrimSpace(data.Goal)), " ", "_"))
+	}
+	if !goalType.IsValid() {
+		return models.Goal{}, invalidf("invalid goal %q", data.Goal)
+	}
+
+	if data.GoalRate < 0 || data.GoalRate > models.MaxGoalRate {
+		return models.Goal{}, invalidf("goal rate must be between 0 and %.1f kg/week", models.MaxGoalRate)
+	}
+	if data.GoalProtein < 0 {
+		return models.Goal{}, invalidf("protein target cannot be negative")
+	}
+
+	return models.Goal{
+		Type:         goalType,
+		Rate:         data.GoalRate,
+		ProteinPerKg: data.GoalProtein,
+	}, nil
+}
+
+func newNutritionValues(totals models.NutritionalTotals) NutritionValues {
+	return NutritionValues{
+		Calories: totals.Calories,
+		Proteins: totals.Proteins,
+		Carbs:    totals.Carbs,
+		Fats:     totals.Fats,
+		Fiber:    totals.Fiber,
+	}
+}
+
+func newProfileResponse(user *models.User) ProfileResponseData {
+	return ProfileResponseData{
+		ID:                user.ID,
+		FirstName:         user.FirstName,
+		LastName:          user.LastName,
+		Age:               user.Age,
+		Weight:            user.Weight,
+		Height:            user.Height,
+		Gender:            user.Gender,
+		Goal:              string(user.Goal.Type),
+		GoalRate:          user.Goal.EffectiveRate(),
+		GoalProtein:       user.Goal.EffectiveProteinPerKg(),
+		ActivityLevel:     string(user.ActivityLevel),
+		BMI:               user.CalculateBMI(),
+		BodyFatPerc:       user.EstimateBodyFat(),
+		BMR:               user.CalculateBMR(),
+		BMRHarrisBenedict: user.CalculateBMRHarrisBenedict(),
+		BMRKatchMcArdle:   user.CalculateBMRKatchMcArdle(),
+		TDEE:              user.CalculateTDEE(),
+		Targets:           newNutritionValues(user.CalculateTargets()),
 	}
 }
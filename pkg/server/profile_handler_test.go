@@ -0,0 +1,87 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestCreateProfileValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(*CreateProfileData)
+		wantStatus int
+	}{
+		{"valid", func(*CreateProfileData) {}, http.StatusCreated},
+		{"names only", func(d *CreateProfileData) { *d = CreateProfileData{FirstName: "Jane", LastName: "Doe"} }, http.StatusBadRequest},
+		{"zero height", func(d *CreateProfileData) { d.Height = 0 }, http.StatusBadRequest},
+		{"negative weight", func(d *CreateProfileData) { d.Weight = -60 }, http.StatusBadRequest},
+		{"zero age", func(d *CreateProfileData) { d.Age = 0 }, http.StatusBadRequest},
+		{"age too high", func(d *CreateProfileData) { d.Age = 200 }, http.StatusBadRequest},
+		{"invalid goal", func(d *CreateProfileData) { d.Goal = "fly" }, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, userDB := newTestServer(t)
+			data := validProfile
+			tt.change(&data)
+
+			var profile ProfileResponseData
+			status := doJSON(t, s, http.MethodPost, "/users", data, &profile)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+
+			users, err := userDB.ListUsers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantUsers := 0
+			if status == http.StatusCreated {
+				wantUsers = 1
+			}
+			if len(users) != wantUsers {
+				t.Errorf("%d users saved, want %d", len(users), wantUsers)
+			}
+			if status == http.StatusCreated {
+				if math.Abs(profile.BMI-22.04) > 0.01 || profile.BMR <= 0 {
+					t.Errorf("profile = %+v, want estimates", profile)
+				}
+				if latest := userDB.GetLatestWeighIn(profile.ID); latest == nil || latest.Weight != data.Weight {
+					t.Errorf("latest weigh-in = %+v, want %v kg", latest, data.Weight)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	s, userDB := newTestServer(t)
+	userID := createTestUser(t, s)
+	path := "/users/" + strconv.FormatInt(userID, 10)
+
+	data := validProfile
+	data.Weight, data.Height = 0, 0
+	if status := doJSON(t, s, http.MethodPut, path, data, nil); status != http.StatusBadRequest {
+		t.Errorf("PUT status = %d, want %d", status, http.StatusBadRequest)
+	}
+	user := userDB.GetUser(userID)
+	if user.Height != validProfile.Height {
+		t.Errorf("height = %v, want %v unchanged", user.Height, validProfile.Height)
+	}
+	if latest := userDB.GetLatestWeighIn(userID); latest.Weight != validProfile.Weight {
+		t.Errorf("latest weigh-in = %v kg, want %v unchanged", latest.Weight, validProfile.Weight)
+	}
+
+	data = validProfile
+	data.Weight = 58
+	var profile ProfileResponseData
+	if status := doJSON(t, s, http.MethodPut, path, data, &profile); status != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d", status, http.StatusOK)
+	}
+	if profile.Weight != 58 {
+		t.Errorf("weight = %v, want 58", profile.Weight)
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"slices"
+	"strings"
+)
+
+// quickAddMatches is the number of foods offered for each food of a
+// quick-add line
+const quickAddMatches = 5
+
+// Bounds of the work done to match the foods of a quick-add line, shared by
+// all its foods: the searches, and the foods whose details are fetched when
+// search results lack their portions
+const (
+	quickAddSearches = 10
+	quickAddDetails  = 2 * quickAddMatches
+)
+
+// quickAddBudget is what is left of the bounds of a quick-add line
+type quickAddBudget struct {
+	searches int
+	// details are the foods whose details were fetched, nil when it failed
+	details map[string]*models.Food
+}
+
+func (s *Server) handleParseFoodLine(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(FoodLineData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	var meals []string
+	for _, meal := range dailyLog.Meals {
+		meals = append(meals, meal.Name)
+	}
+	line, err := models.ParseFoodLine(data.Text, meals)
+	if err != nil {
+		return Response{Error: invalidf("%v", err)}
+	}
+
+	resp := FoodLineResponse{Meal: line.Meal, MealIndex: -1}
+	for i, meal := range dailyLog.Meals {
+		if line.Meal != "" && strings.EqualFold(meal.Name, line.Meal) {
+			resp.Meal, resp.MealIndex = meal.Name, i
+			break
+		}
+	}
+
+	budget := &quickAddBudget{searches: quickAddSearches, details: make(map[string]*models.Food)}
+	for _, entry := range line.Entries {
+		matches, err := s.matchFoodEntry(ctx, entry, budget)
+		if err != nil {
+			return Response{Error: err}
+		}
+		resp.Entries = append(resp.Entries, FoodEntryMatches{Text: entry.Text, Matches: matches})
+	}
+	return Response{Data: resp}
+}
+
+// matchFoodEntry searches the foods of a quick-add entry, stopping at the
+// first search text finding foods. The entry is searched with a household
+// measure first, as "1 slice" of "toast" for "1 slice toast", then as typed.
+// The foods found are matched with the measure when they have it, else as a
+// count of the food. Foods found whose quantity cannot be converted fail the
+// entry.
+func (s *Server) matchFoodEntry(ctx context.Context, entry models.FoodEntry, budget *quickAddBudget) ([]FoodMatch, error) {
+	readings := []models.FoodEntry{entry}
+	if measured, ok := entry.HouseholdMeasure(); ok {
+		readings = []models.FoodEntry{measured, entry}
+	}
+
+	var texts []string
+	for _, reading := range readings {
+		for _, text := range reading.SearchTexts() {
+			if !slices.Contains(texts, text) {
+				texts = append(texts, text)
+			}
+		}
+	}
+
+	var found []models.Food
+	for _, text := range texts {
+		if budget.searches == 0 {
+			return nil, invalidf("%s: too many foods in one line, add the others separately", entry.Text)
+		}
+		budget.searches--
+
+		result, _, err := s.searchFoods(ctx, models.FoodQuery{Text: text, PageSize: quickAddMatches}.Normalize(), nil)
+		if err != nil {
+			return nil, upstreamf("search failed: %v", err)
+		}
+		if len(result.Foods) > 0 {
+			found = result.Foods[:min(len(result.Foods), quickAddMatches)]
+			break
+		}
+	}
+
+	var quantityErr error
+	for _, reading := range readings {
+		var matches []FoodMatch
+		for _, food := range found {
+			grams, err := reading.Grams(&food)
+			// Search results may lack the portions of the food
+			if err != nil && len(food.Portions) == 0 {
+				if detailed, ok := s.cachedFoodDetails(ctx, budget, food.ID); ok {
+					food = *detailed
+					grams, err = reading.Grams(&food)
+				}
+			}
+			if err != nil {
+				quantityErr = err
+				continue
+			}
+			matches = append(matches, FoodMatch{Food: newFoodItem(food), Quantity: grams})
+		}
+		if len(matches) > 0 {
+			return matches, nil
+		}
+	}
+	if quantityErr != nil {
+		return nil, invalidf("%s: %v", entry.Text, quantityErr)
+	}
+	return nil, nil
+}
+
+// cachedFoodDetails fetches the details of a food for matchFoodEntry, once
+// per food and within the details budget of the line
+func (s *Server) cachedFoodDetails(ctx context.Context, budget *quickAddBudget, id string) (*models.Food, bool) {
+	if food, ok := budget.details[id]; ok {
+		return food, food != nil
+	}
+	if len(budget.details) >= quickAddDetails {
+		return nil, false
+	}
+
+	food, err := s.getFoodDetails(ctx, id)
+	if err != nil {
+		food = nil
+	}
+	budget.details[id] = food
+	return food, food != nil
+}
+
+func (s *Server) handleAddFoodLine(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(AddFoodLineData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+	if len(data.Foods) == 0 {
+		return Response{Error: invalidf("no food to add")}
+	}
+
+	// Foods are fetched before the log is locked, as sources may be slow
+	var foods []*models.Food
+	for _, amount := range data.Foods {
+		if amount.Quantity <= 0 {
+			return Response{Error: invalidf("quantity must be positive")}
+		}
+		food, err := s.getFoodDetails(ctx, amount.FoodID)
+		if err != nil {
+			return Response{Error: err}
+		}
+		foods = append(foods, food)
+	}
+
+	defer s.lockUser(data.UserID)()
+	dailyLog, err := s.getDailyLog(data.UserID, data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	mealIndex := data.MealIndex
+	if mealIndex == -1 {
+		name := strings.TrimSpace(data.Meal)
+		if name == "" {
+			return Response{Error: invalidf("meal name is required")}
+		}
+		dailyLog.Meals = append(dailyLog.Meals, &models.Meal{Name: name, Time: mealTime(dailyLog.Date), Foods: make([]models.FoodQuantity, 0)})
+		mealIndex = len(dailyLog.Meals) - 1
+	}
+	meal, err := getMeal(dailyLog, mealIndex)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	for i, food := range foods {
+		meal.AddFood(food, data.Foods[i].Quantity)
+	}
+	if err := s.userDB.SaveDailyLog(dailyLog); err != nil {
+		return Response{Error: fmt.Errorf("failed to save foods: %v", err)}
+	}
+	return Response{Data: AddFoodLineResponse{Meal: meal.Name, MealIndex: mealIndex}}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"testing"
+)
+
+// searchLog is a food source knowing foods by exact search text, which
+// records the texts searched
+type searchLog struct {
+	foods    map[string][]models.Food
+	searched []string
+}
+
+func (s *searchLog) Name() string {
+	return "test"
+}
+
+func (s *searchLog) SearchFoods(query models.FoodQuery) (models.SearchResult, error) {
+	s.searched = append(s.searched, query.Text)
+	foods := s.foods[query.Text]
+	return models.SearchResult{Foods: foods, TotalHits: len(foods)}, nil
+}
+
+func (s *searchLog) GetFoodDetails(id string) (*models.Food, error) {
+	return nil, fmt.Errorf("%w: %s", models.ErrFoodNotFound, id)
+}
+
+func TestParseFoodLineSearches(t *testing.T) {
+	source := &searchLog{foods: map[string][]models.Food{
+		"egg":   {{ID: "1", Name: "Egg, whole", Portions: []models.Portion{{Amount: 1, Description: "large", GramWeight: 50}}}},
+		"toast": {{ID: "2", Name: "Toast", Portions: []models.Portion{{Amount: 1, Description: "slice", GramWeight: 30}}}},
+		"apple": {{ID: "3", Name: "Apple", Portions: []models.Portion{{Amount: 1, Description: "medium", GramWeight: 180}}}},
+	}}
+	s, _ := newTestServer(t, source)
+	userID := createTestUser(t, s)
+
+	tests := []struct {
+		text         string
+		wantSearched []string
+		wantFood     string
+		wantQuantity float64
+	}{
+		// The singular is only searched when the food as typed finds nothing
+		{"2 eggs", []string{"eggs", "egg"}, "test_1", 100},
+		// The household measure finds the food first
+		{"2 slice toast", []string{"toast"}, "test_2", 60},
+		// Foods lacking the measure are counted, without searching again
+		{"2 big apples", []string{"apples", "apple"}, "test_3", 360},
+		{"1 kiwi", []string{"kiwi"}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			source.searched = nil
+			resp := s.dispatch(context.Background(), ReqParseFoodLine, FoodLineData{UserID: userID, Text: tt.text})
+			if resp.Error != nil {
+				t.Fatal(resp.Error)
+			}
+			if fmt.Sprint(source.searched) != fmt.Sprint(tt.wantSearched) {
+				t.Errorf("searched %q, want %q", source.searched, tt.wantSearched)
+			}
+
+			matches := resp.Data.(FoodLineResponse).Entries[0].Matches
+			if tt.wantFood == "" {
+				if len(matches) != 0 {
+					t.Errorf("matches = %+v, want none", matches)
+				}
+				return
+			}
+			if len(matches) != 1 || matches[0].Food.ID != tt.wantFood || matches[0].Quantity != tt.wantQuantity {
+				t.Errorf("matches = %+v, want %vg of %s", matches, tt.wantQuantity, tt.wantFood)
+			}
+		})
+	}
+}
+
+func TestParseFoodLineSearchBudget(t *testing.T) {
+	source := &searchLog{}
+	s, _ := newTestServer(t, source)
+	userID := createTestUser(t, s)
+
+	text := "kiwi1"
+	for i := 2; i <= quickAddSearches+1; i++ {
+		text += fmt.Sprintf(", kiwi%d", i)
+	}
+	resp := s.dispatch(context.Background(), ReqParseFoodLine, FoodLineData{UserID: userID, Text: text})
+	if KindOf(resp.Error) != ErrInvalid {
+		t.Errorf("line of %d foods error = %v, want an invalid request", quickAddSearches+1, resp.Error)
+	}
+	if len(source.searched) != quickAddSearches {
+		t.Errorf("%d searches, want %d", len(source.searched), quickAddSearches)
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"nutritionapp/pkg/models"
+	"strings"
+)
+
+func (s *Server) handleCreateRecipe(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(RecipeData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.recipes()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	recipe, err := s.newRecipe(ctx, data, nil)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	if err := store.CreateRecipe(recipe); err != nil {
+		return Response{Error: fmt.Errorf("failed to create recipe: %v", err)}
+	}
+
+	recipe.ID = source.Name() + foodIDSeparator + recipe.ID
+	return Response{Data: RecipeResponse{Recipe: newRecipeInfo(recipe)}}
+}
+
+func (s *Server) handleUpdateRecipe(ctx context.Context, untypedData any) Response {
+	data, ok := untypedData.(RecipeData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.recipes()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	sourceName, sourceID, ok := strings.Cut(data.ID, foodIDSeparator)
+	if !ok || sourceName != source.Name() {
+		return Response{Error: invalidf("%q is not a recipe", data.ID)}
+	}
+
+	current, err := store.GetRecipe(sourceID)
+	if errors.Is(err, models.ErrFoodNotFound) {
+		return Response{Error: notFoundf("unknown recipe %q", data.ID)}
+	}
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to get recipe: %v", err)}
+	}
+
+	recipe, err := s.newRecipe(ctx, data, current)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	recipe.ID = sourceID
+	err = store.UpdateRecipe(recipe)
+	if errors.Is(err, models.ErrFoodNotFound) {
+		return Response{Error: notFoundf("unknown recipe %q", data.ID)}
+	}
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to update recipe: %v", err)}
+	}
+
+	recipe.ID = data.ID
+	return Response{Data: RecipeResponse{Recipe: newRecipeInfo(recipe)}}
+}
+
+func (s *Server) handleListRecipes(untypedData any) Response {
+	if _, ok := untypedData.(ListRecipesData); !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	source, store, err := s.recipes()
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	recipes, err := store.ListRecipes()
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to list recipes: %v", err)}
+	}
+
+	infos := make([]RecipeInfo, 0, len(recipes))
+	for _, recipe := range recipes {
+		recipe.ID = source.Name() + foodIDSeparator + recipe.ID
+		infos = append(infos, newRecipeInfo(&recipe))
+	}
+	return Response{Data: RecipeListResponse{Recipes: infos}}
+}
+
+// newRecipe validates a recipe request and fetches the foods of its
+// ingredients. When updating, the foods already in the current recipe are
+// reused rather than fetched again.
+func (s *Server) newRecipe(ctx context.Context, data RecipeData, current *models.Recipe) (*models.Recipe, error) {
+	recipe := &models.Recipe{
+		Name:        strings.TrimSpace(data.Name),
+		Servings:    data.Servings,
+		YieldWeight: data.YieldWeight,
+	}
+	if recipe.Name == "" {
+		return nil, invalidf("name is required")
+	}
+	if recipe.Servings <= 0 {
+		return nil, invalidf("servings must be positive")
+	}
+	if recipe.YieldWeight < 0 {
+		return nil, invalidf("yield weight cannot be negative")
+	}
+	if len(data.Ingredients) == 0 {
+		return nil, invalidf("a recipe needs at least one ingredient")
+	}
+
+	known := make(map[string]*models.Food)
+	if current != nil {
+		for _, item := range current.Ingredients {
+			known[item.Food.ID] = item.Food
+		}
+	}
+
+	for _, ingredient := range data.Ingredients {
+		if data.ID != "" && ingredient.FoodID == data.ID {
+			return nil, invalidf("a recipe cannot be one of its own ingredients")
+		}
+
+		food, ok := known[ingredient.FoodID]
+		if !ok {
+			var err error
+			if food, err = s.getFoodDetails(ctx, ingredient.FoodID); err != nil {
+				return nil, err
+			}
+			known[food.ID] = food
+		}
+
+		quantity, err := foodQuantity(food, ingredient.Quantity, ingredient.Amount)
+		if err != nil {
+			return nil, err
+		}
+		recipe.Ingredients = append(recipe.Ingredients, models.FoodQuantity{Food: food, Quantity: quantity})
+	}
+	return recipe, nil
+}
+
+// newRecipeInfo describes a recipe with its nutritional values for the whole
+// dish, per serving and per 100g
+func newRecipeInfo(recipe *models.Recipe) RecipeInfo {
+	info := RecipeInfo{
+		ID:           recipe.ID,
+		Name:         recipe.Name,
+		Servings:     recipe.Servings,
+		YieldWeight:  recipe.YieldWeight,
+		RawWeight:    recipe.RawWeight(),
+		CookedWeight: recipe.CookedWeight(),
+		Ingredients:  make([]IngredientInfo, 0, len(recipe.Ingredients)),
+	}
+
+	for _, item := range recipe.Ingredients {
+		info.Ingredients = append(info.Ingredients, IngredientInfo{
+			FoodID:   item.Food.ID,
+			Name:     item.Food.Name,
+			Quantity: item.Quantity,
+			Calories: item.Food.Calories * item.Quantity / 100,
+		})
+	}
+
+	totals := recipe.CalculateTotals()
+	info.Total = newNutritionValues(totals)
+	if recipe.Servings > 0 {
+		info.PerServing = scaleNutritionValues(info.Total, 1/recipe.Servings)
+	}
+	if info.CookedWeight > 0 {
+		info.Per100g = scaleNutritionValues(info.Total, 100/info.CookedWeight)
+	}
+	return info
+}
+
+func scaleNutritionValues(values NutritionValues, multiplier float64) NutritionValues {
+	return NutritionValues{
+		Calories: values.Calories * multiplier,
+		Proteins: values.Proteins * multiplier,
+		Carbs:    values.Carbs * multiplier,
+		Fats:     values.Fats * multiplier,
+		Fiber:    values.Fiber * multiplier,
+	}
+}
@@ -1,30 +1,242 @@
 package server
 
 import (
+	"fmt"
 	"nutritionapp/pkg/models"
+	"sort"
+	"strings"
 	"time"
 )
 
+// maxReportDays is the longest period a report can cover
+const maxReportDays = 366
+
 func (s *Server) handleGetReport(untypedData any) Response {
-	dailyLog := s.userDB.GetDailyLog(time.Now())
-	var totals models.NutritionalTotals
+	data, ok := untypedData.(GetReportData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	user, err := s.getUser(data.UserID)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	day, err := parseDate(data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+	dailyLog := s.userDB.GetDailyLog(user.ID, day)
+
+	totals := dailyLog.CalculateTotals()
 
-	for _, meal := range dailyLog.Meals {
-		mealTotals := meal.CalculateTotals()
-		totals.Calories += mealTotals.Calories
-		totals.Proteins += mealTotals.Proteins
-		totals.Carbs += mealTotals.Carbs
-		totals.Fats += mealTotals.Fats
-		totals.Fiber += mealTotals.Fiber
+	// Without targets, as when the profile measurements are unknown,
+	// nothing is remaining
+	targets := user.CalculateTargets()
+	var remaining NutritionValues
+	if targets.Calories > 0 {
+		remaining = NutritionValues{
+			Calories: targets.Calories - totals.Calories,
+			Proteins: targets.Proteins - totals.Proteins,
+			Carbs:    targets.Carbs - totals.Carbs,
+			Fats:     targets.Fats - totals.Fats,
+			Fiber:    targets.Fiber - totals.Fiber,
+		}
+	}
+
+	// Weight progress over the four weeks up to the report date
+	weighIns, err := s.userDB.ListWeighIns(user.ID, dailyLog.Date.AddDate(0, 0, -27))
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to load weight history: %v", err)}
+	}
+	for len(weighIns) > 0 && weighIns[len(weighIns)-1].Date.Format(DateFormat) > dailyLog.Date.Format(DateFormat) {
+		weighIns = weighIns[:len(weighIns)-1]
+	}
+
+	var weightTrend float64
+	if trend := models.WeightTrend(weighIns); len(trend) > 0 {
+		weightTrend = trend[len(trend)-1]
 	}
 
 	return Response{
 		Data: ReportResponse{
-			Calories: totals.Calories,
-			Proteins: totals.Proteins,
-			Carbs:    totals.Carbs,
-			Fats:     totals.Fats,
-			Fiber:    totals.Fiber,
+			Date:         dailyLog.Date.Format(DateFormat),
+			Calories:     totals.Calories,
+			Proteins:     totals.Proteins,
+			Carbs:        totals.Carbs,
+			Fats:         totals.Fats,
+			Fiber:        totals.Fiber,
+			Targets:      newNutritionValues(targets),
+			Remaining:    remaining,
+			WeightTrend:  weightTrend,
+			WeeklyChange: models.WeeklyChange(weighIns),
+			GoalRate:     user.Goal.WeeklyTarget(),
+			Nutrients:    newNutrientValues(selectNutrients(totals.Nutrients, data.Nutrients), 0),
 		},
 	}
 }
+
+func (s *Server) handleGetPeriodReport(untypedData any) Response {
+	data, ok := untypedData.(PeriodReportData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	user, err := s.getUser(data.UserID)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	from, err := parseDate(data.From)
+	if err != nil {
+		return Response{Error: err}
+	}
+	to, err := parseDate(data.To)
+	if err != nil {
+		return Response{Error: err}
+	}
+
+	// Days that have not happened yet cannot be missed
+	if today := time.Now(); to.After(today) {
+		to = today
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	if to.Before(from) {
+		return Response{Error: invalidf("report period ends before it starts")}
+	}
+	if to.Sub(from).Hours()/24 >= maxReportDays {
+		return Response{Error: invalidf("report period cannot exceed %d days", maxReportDays)}
+	}
+
+	dailyLogs, err := s.userDB.GetDailyLogs(data.UserID, from, to)
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to load daily logs: %v", err)}
+	}
+	logsByDate := make(map[string]*models.DailyLog)
+	for _, dailyLog := range dailyLogs {
+		logsByDate[dailyLog.Date.Format(DateFormat)] = dailyLog
+	}
+
+	report := PeriodReportResponse{
+		From:    from.Format(DateFormat),
+		To:      to.Format(DateFormat),
+		Targets: newNutritionValues(user.CalculateTargets()),
+	}
+
+	var totals models.NutritionalTotals
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateFormat)
+		report.Days++
+
+		dailyLog, ok := logsByDate[date]
+		if !ok || !dailyLog.HasFood() {
+			report.MissedDays = append(report.MissedDays, date)
+			report.DailyTotals = append(report.DailyTotals, DayTotals{Date: date})
+			continue
+		}
+
+		dayTotals := dailyLog.CalculateTotals()
+		totals.Add(dayTotals)
+		entry := DayTotals{Date: date, Logged: true, NutritionValues: newNutritionValues(dayTotals)}
+		report.DailyTotals = append(report.DailyTotals, entry)
+
+		if report.DaysLogged == 0 || entry.Calories < report.MinDay.Calories {
+			report.MinDay = entry
+		}
+		if report.DaysLogged == 0 || entry.Calories > report.MaxDay.Calories {
+			report.MaxDay = entry
+		}
+		report.DaysLogged++
+	}
+
+	report.Totals = newNutritionValues(totals)
+	if report.DaysLogged > 0 {
+		n := float64(report.DaysLogged)
+		report.Averages = NutritionValues{
+			Calories: totals.Calories / n,
+			Proteins: totals.Proteins / n,
+			Carbs:    totals.Carbs / n,
+			Fats:     totals.Fats / n,
+			Fiber:    totals.Fiber / n,
+		}
+	}
+
+	report.Nutrients = newNutrientValues(selectNutrients(totals.Nutrients, data.Nutrients), report.DaysLogged)
+
+	return Response{Data: report}
+}
+
+// selectNutrients picks the requested nutrients from the totals, keeping the
+// requested order. Nutrients that were not eaten are reported as 0.
+func selectNutrients(totals map[string]models.Nutrient, requested []string) []models.Nutrient {
+	if len(requested) == 1 && strings.EqualFold(requested[0], "all") {
+		var all []models.Nutrient
+		for _, n := range totals {
+			if !isMacronutrient(n.Number) {
+				all = append(all, n)
+			}
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+		return all
+	}
+
+	if len(requested) == 0 {
+		for _, n := range models.DefaultNutrients {
+			requested = append(requested, n.Number)
+		}
+	}
+
+	var selected []models.Nutrient
+	for _, key := range requested {
+		selected = append(selected, findNutrient(totals, key))
+	}
+	return selected
+}
+
+// findNutrient looks a nutrient up in the totals by alias, such as "sodium",
+// by number or by FDC name, falling back to the nutrient with no amount
+func findNutrient(totals map[string]models.Nutrient, key string) models.Nutrient {
+	if known, err := models.LookupNutrient(key); err == nil {
+		if n, ok := totals[known.Number]; ok {
+			return n
+		}
+		if known.Name == "" {
+			known.Name = key
+		}
+		return known
+	}
+	for _, n := range totals {
+		if strings.EqualFold(n.Name, key) {
+			return n
+		}
+	}
+	for _, n := range models.DefaultNutrients {
+		if strings.EqualFold(n.Name, key) {
+			return n
+		}
+	}
+	return models.Nutrient{Number: key, Name: key}
+}
+
+func isMacronutrient(number string) bool {
+	switch number {
+	case models.NutrientEnergy, models.NutrientProtein, models.NutrientCarbs, models.NutrientFat, models.NutrientFiber:
+		return true
+	}
+	return false
+}
+
+// newNutrientValues converts nutrient totals, averaging them over days when
+// it is positive
+func newNutrientValues(nutrients []models.Nutrient, days int) []NutrientValue {
+	values := make([]NutrientValue, 0, len(nutrients))
+	for _, n := range nutrients {
+		value := NutrientValue{Number: n.Number, Name: n.Name, Unit: n.Unit, Amount: n.Amount}
+		if days > 0 {
+			value.Average = n.Amount / float64(days)
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+	"nutritionapp/pkg/models"
+	"strconv"
+	"testing"
+)
+
+func TestGetReportUnknownMeasurements(t *testing.T) {
+	s, userDB := newTestServer(t)
+	// The placeholder profile migrated logs are assigned to
+	user := &models.User{FirstName: "Default", LastName: "User", Goal: models.Goal{Type: models.GoalMaintenance}}
+	if err := userDB.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	path := "/users/" + strconv.FormatInt(user.ID, 10)
+
+	if status := doJSON(t, s, http.MethodPost, path+"/meals", AddMealData{Name: "Breakfast"}, nil); status != http.StatusNoContent {
+		t.Fatalf("POST meals status = %d, want %d", status, http.StatusNoContent)
+	}
+
+	var report ReportResponse
+	if status := doJSON(t, s, http.MethodGet, path+"/report", nil, &report); status != http.StatusOK {
+		t.Fatalf("GET report status = %d, want %d", status, http.StatusOK)
+	}
+	if report.Targets != (NutritionValues{}) || report.Remaining != (NutritionValues{}) {
+		t.Errorf("report targets = %+v, remaining = %+v, want none", report.Targets, report.Remaining)
+	}
+
+	var period PeriodReportResponse
+	if status := doJSON(t, s, http.MethodGet, path+"/report/period", nil, &period); status != http.StatusOK {
+		t.Fatalf("GET period report status = %d, want %d", status, http.StatusOK)
+	}
+}
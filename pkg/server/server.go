@@ -1,23 +1,35 @@
 package server
 
 import (
-	"fmt"
+	"context"
 	"nutritionapp/pkg/db"
-	"nutritionapp/pkg/fdc"
+	"nutritionapp/pkg/models"
+	"sync"
+	"time"
 )
 
+// DateFormat is the layout of the dates exchanged in requests and responses.
+// An empty date in a request means today.
+const DateFormat = "2006-01-02"
+
 type Server struct {
-	userDB        db.UserDatabase
-	foodProcessor *fdc.FoodProcessor
-	requests      chan Request
+	userDB      db.UserDatabase
+	foodSources []FoodSource
+	requests    chan Request
+
+	// userLocks serialize the changes of each user, keyed by user ID
+	userLocksMu sync.Mutex
+	userLocks   map[int64]*sync.Mutex
 }
 
-// NewServer creates a new server instance
-func NewServer(userDB db.UserDatabase, foodProcessor *fdc.FoodProcessor, requests chan Request) *Server {
+// NewServer creates a new server instance. Food searches query the sources
+// in order, earlier sources winning when several know the same food.
+func NewServer(userDB db.UserDatabase, foodSources []FoodSource, requests chan Request) *Server {
 	return &Server{
-		userDB:        userDB,
-		foodProcessor: foodProcessor,
-		requests:      requests,
+		userDB:      userDB,
+		foodSources: foodSources,
+		requests:    requests,
+		userLocks:   make(map[int64]*sync.Mutex),
 	}
 }
 
@@ -40,10 +52,19 @@ func (s *Server) SendRequest(reqType string, data interface{}) Response {
 }
 
 func (s *Server) handleRequest(req Request) {
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req.Return <- s.dispatch(ctx, req.Type, req.Data)
+}
+
+// dispatch runs the handler matching a request type. Handlers reaching the
+// food sources pass them ctx.
+func (s *Server) dispatch(ctx context.Context, reqType string, data any) Response {
 	var resp Response
-	data := req.Data
 
-	switch req.Type {
+	switch reqType {
 	case ReqCreateProfile:
 		resp = s.handleCreateProfile(data)
 	case ReqGetProfile:
@@ -55,14 +76,117 @@ func (s *Server) handleRequest(req Request) {
 	case ReqListMeals:
 		resp = s.handleListMeals(data)
 	case ReqSearchFood:
-		resp = s.handleSearchFood(data)
+		resp = s.handleSearchFood(ctx, data)
 	case ReqAddFood:
-		resp = s.handleAddFood(data)
+		resp = s.handleAddFood(ctx, data)
 	case ReqGetReport:
 		resp = s.handleGetReport(data)
+	case ReqListUsers:
+		resp = s.handleListUsers(data)
+	case ReqDeleteMeal:
+		resp = s.handleDeleteMeal(data)
+	case ReqRenameMeal:
+		resp = s.handleRenameMeal(data)
+	case ReqRemoveFood:
+		resp = s.handleRemoveFood(data)
+	case ReqUpdateFoodQuantity:
+		resp = s.handleUpdateFoodQuantity(data)
+	case ReqMoveFood:
+		resp = s.handleMoveFood(data)
+	case ReqLogWeight:
+		resp = s.handleLogWeight(data)
+	case ReqWeightHistory:
+		resp = s.handleWeightHistory(data)
+	case ReqGetPeriodReport:
+		resp = s.handleGetPeriodReport(data)
+	case ReqLookupBarcode:
+		resp = s.handleLookupBarcode(ctx, data)
+	case ReqCreateCustomFood:
+		resp = s.handleCreateCustomFood(data)
+	case ReqUpdateCustomFood:
+		resp = s.handleUpdateCustomFood(data)
+	case ReqListCustomFoods:
+		resp = s.handleListCustomFoods(data)
+	case ReqCreateRecipe:
+		resp = s.handleCreateRecipe(ctx, data)
+	case ReqUpdateRecipe:
+		resp = s.handleUpdateRecipe(ctx, data)
+	case ReqListRecipes:
+		resp = s.handleListRecipes(data)
+	case ReqParseFoodLine:
+		resp = s.handleParseFoodLine(ctx, data)
+	case ReqAddFoodLine:
+		resp = s.handleAddFoodLine(ctx, data)
+	case ReqRecentFoods:
+		resp = s.handleFoodUsage(data, s.userDB.RecentFoods)
+	case ReqFavoriteFoods:
+		resp = s.handleFoodUsage(data, s.userDB.FavoriteFoods)
+	case ReqStarFood:
+		resp = s.handleStarFood(ctx, data)
+	case ReqCopyMeals:
+		resp = s.handleCopyMeals(data)
+	case ReqSaveMealTemplate:
+		resp = s.handleSaveMealTemplate(data)
+	case ReqListMealTemplates:
+		resp = s.handleListMealTemplates(data)
+	case ReqApplyMealTemplate:
+		resp = s.handleApplyMealTemplate(data)
 	default:
-		resp = Response{Error: fmt.Errorf("unknown request type: %s", req.Type)}
+		resp = Response{Error: invalidf("unknown request type: %s", reqType)}
+	}
+
+	return resp
+}
+
+// lockUser locks the changes of a user and returns the unlock function.
+// Requests are handled concurrently, so handlers changing a daily log or a
+// profile hold the lock from reading it to saving it, lest concurrent
+// changes be lost.
+func (s *Server) lockUser(userID int64) func() {
+	s.userLocksMu.Lock()
+	lock, ok := s.userLocks[userID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.userLocks[userID] = lock
 	}
+	s.userLocksMu.Unlock()
 
-	req.Return <- resp
+	lock.Lock()
+	return lock.Unlock
+}
+
+// getDailyLog checks that the user exists and loads their log for the
+// request date
+func (s *Server) getDailyLog(userID int64, date string) (*models.DailyLog, error) {
+	if _, err := s.getUser(userID); err != nil {
+		return nil, err
+	}
+
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userDB.GetDailyLog(userID, day), nil
+}
+
+// getMeal returns the meal at the given index of a daily log
+func getMeal(dailyLog *models.DailyLog, mealIndex int) (*models.Meal, error) {
+	if mealIndex < 0 || mealIndex >= len(dailyLog.Meals) {
+		return nil, notFoundf("invalid meal index")
+	}
+	return dailyLog.Meals[mealIndex], nil
+}
+
+// parseDate parses a request date, defaulting to today when it is empty
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Now(), nil
+	}
+
+	t, err := time.ParseInLocation(DateFormat, date, time.Local)
+	if err != nil {
+		return time.Time{}, invalidf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return t, nil
 }
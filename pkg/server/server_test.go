@@ -0,0 +1,66 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"nutritionapp/pkg/db"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestServer creates a server over a new database, searching the given
+// food sources
+func newTestServer(t *testing.T, sources ...FoodSource) (*Server, *db.SQLiteDB) {
+	t.Helper()
+	userDB, err := db.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(userDB, sources, nil), userDB
+}
+
+// doJSON sends a request with a JSON body, nil for none, to the HTTP API of
+// the server and decodes the JSON response into out, if not nil. It returns
+// the response status.
+func doJSON(t *testing.T, s *Server, method, path string, body, out any) int {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(method, path, &reqBody))
+	if out != nil && rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid response %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// validProfile is a profile every measurement of which is known
+var validProfile = CreateProfileData{
+	FirstName: "Jane",
+	LastName:  "Doe",
+	Age:       30,
+	Weight:    60,
+	Height:    165,
+	Gender:    "female",
+}
+
+// createTestUser creates a profile through the API and returns its ID
+func createTestUser(t *testing.T, s *Server) int64 {
+	t.Helper()
+	var profile ProfileResponseData
+	if status := doJSON(t, s, http.MethodPost, "/users", validProfile, &profile); status != http.StatusCreated {
+		t.Fatalf("POST /users status = %d, want %d", status, http.StatusCreated)
+	}
+	return profile.ID
+}
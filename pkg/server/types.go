@@ -1,10 +1,15 @@
 package server
 
+import "context"
+
 // Request represents a request from client to server
 type Request struct {
-	Type   string
-	Data   any
-	Return chan Response
+	Type string
+	Data any
+	// Context cancels the food source requests made for the request when
+	// done. Nil means they are never canceled.
+	Context context.Context
+	Return  chan Response
 }
 
 type Response struct {
@@ -22,52 +27,492 @@ const (
 	ReqSearchFood    = "search_food"
 	ReqAddFood       = "add_food"
 	ReqGetReport     = "get_report"
+	ReqListUsers     = "list_users"
+
+	ReqDeleteMeal         = "delete_meal"
+	ReqRenameMeal         = "rename_meal"
+	ReqRemoveFood         = "remove_food"
+	ReqUpdateFoodQuantity = "update_food_quantity"
+	ReqMoveFood           = "move_food"
+
+	ReqLogWeight     = "log_weight"
+	ReqWeightHistory = "weight_history"
+
+	ReqGetPeriodReport = "get_period_report"
+
+	ReqLookupBarcode = "lookup_barcode"
+
+	ReqCreateCustomFood = "create_custom_food"
+	ReqUpdateCustomFood = "update_custom_food"
+	ReqListCustomFoods  = "list_custom_foods"
+
+	ReqCreateRecipe = "create_recipe"
+	ReqUpdateRecipe = "update_recipe"
+	ReqListRecipes  = "list_recipes"
+
+	ReqParseFoodLine = "parse_food_line"
+	ReqAddFoodLine   = "add_food_line"
+
+	ReqRecentFoods   = "recent_foods"
+	ReqFavoriteFoods = "favorite_foods"
+	ReqStarFood      = "star_food"
+
+	ReqCopyMeals         = "copy_meals"
+	ReqSaveMealTemplate  = "save_meal_template"
+	ReqListMealTemplates = "list_meal_templates"
+	ReqApplyMealTemplate = "apply_meal_template"
 )
 
+// requestPayloads creates an empty payload for each request type, to decode
+// requests received over the network. Types without a payload map to nil.
+var requestPayloads = map[string]func() any{
+	ReqCreateProfile:      payload[CreateProfileData],
+	ReqGetProfile:         payload[GetProfileData],
+	ReqUpdateProfile:      payload[UpdateProfileData],
+	ReqAddMeal:            payload[AddMealData],
+	ReqListMeals:          payload[ListMealsData],
+	ReqSearchFood:         payload[SearchFoodData],
+	ReqAddFood:            payload[AddFoodData],
+	ReqGetReport:          payload[GetReportData],
+	ReqListUsers:          nil,
+	ReqDeleteMeal:         payload[DeleteMealData],
+	ReqRenameMeal:         payload[RenameMealData],
+	ReqRemoveFood:         payload[RemoveFoodData],
+	ReqUpdateFoodQuantity: payload[UpdateFoodQuantityData],
+	ReqMoveFood:           payload[MoveFoodData],
+	ReqLogWeight:          payload[LogWeightData],
+	ReqWeightHistory:      payload[WeightHistoryData],
+	ReqGetPeriodReport:    payload[PeriodReportData],
+	ReqLookupBarcode:      payload[LookupBarcodeData],
+	ReqCreateCustomFood:   payload[CustomFoodData],
+	ReqUpdateCustomFood:   payload[CustomFoodData],
+	ReqListCustomFoods:    payload[ListCustomFoodsData],
+	ReqCreateRecipe:       payload[RecipeData],
+	ReqUpdateRecipe:       payload[RecipeData],
+	ReqListRecipes:        payload[ListRecipesData],
+	ReqParseFoodLine:      payload[FoodLineData],
+	ReqAddFoodLine:        payload[AddFoodLineData],
+	ReqRecentFoods:        payload[FoodUsageData],
+	ReqFavoriteFoods:      payload[FoodUsageData],
+	ReqStarFood:           payload[StarFoodData],
+	ReqCopyMeals:          payload[CopyMealsData],
+	ReqSaveMealTemplate:   payload[SaveMealTemplateData],
+	ReqListMealTemplates:  payload[ListMealTemplatesData],
+	ReqApplyMealTemplate:  payload[ApplyMealTemplateData],
+}
+
+func payload[T any]() any {
+	return new(T)
+}
+
 // Request Data Types
 type CreateProfileData struct {
-	FirstName string
-	LastName  string
-	Age       int
-	Weight    float64
-	Height    float64
-	Gender    string
-	Goal      string
+	FirstName     string
+	LastName      string
+	Age           int
+	Weight        float64
+	Height        float64
+	Gender        string
+	Goal          string
+	GoalRate      float64 // kg/week, 0 for the goal's default
+	GoalProtein   float64 // g per kg of body weight, 0 for the goal's default
+	ActivityLevel string
+}
+
+type GetProfileData struct {
+	UserID int64
 }
 
 type UpdateProfileData struct {
+	UserID int64
 	CreateProfileData
 }
 
 type AddMealData struct {
-	Name string
+	UserID int64
+	Date   string
+	Name   string
+}
+
+type ListMealsData struct {
+	UserID int64
+	Date   string
 }
 
+// CopyMealsData copies the meals of the From date to Date, or only those
+// named Meal when it is set
+type CopyMealsData struct {
+	UserID int64
+	Date   string
+	From   string
+	Meal   string
+}
+
+// SaveMealTemplateData saves a meal of a day as a template, replacing any
+// template of the same name. Name defaults to the name of the meal.
+type SaveMealTemplateData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+	Name      string
+}
+
+type ListMealTemplatesData struct {
+	UserID int64
+}
+
+// ApplyMealTemplateData adds the meal of a template to a day
+type ApplyMealTemplateData struct {
+	UserID     int64
+	Date       string
+	TemplateID int64
+}
+
+// SearchFoodData searches foods. DataTypes are FDC data type names such as
+// "Branded", generic foods being searched when it is empty. Page starts at 1
+// and Sort is "relevance" or "name"; zero values select the first page of
+// 10 foods, by relevance. Filters such as "protein > 20" or "kcal < 150"
+// keep only the foods that match them, per 100g, among the first foods of
+// each source.
 type SearchFoodData struct {
-	Query string
+	UserID    int64
+	Query     string
+	DataTypes []string
+	Page      int
+	PageSize  int
+	Sort      string
+	Filters   []string
 }
 
+// AddFoodData adds a quantity of a food to a meal, in grams or, when Amount
+// is set, as a household quantity such as "2 slices" or "8 oz"
 type AddFoodData struct {
+	UserID    int64
+	Date      string
 	MealIndex int
 	FoodID    string
 	Quantity  float64
+	Amount    string
 }
 
+type DeleteMealData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+}
+
+type RenameMealData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+	Name      string
+}
+
+type RemoveFoodData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+	FoodIndex int
+}
+
+// UpdateFoodQuantityData changes the quantity of a food item, in grams or,
+// when Amount is set, as a household quantity
+type UpdateFoodQuantityData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+	FoodIndex int
+	Quantity  float64
+	Amount    string
+}
+
+type MoveFoodData struct {
+	UserID      int64
+	Date        string
+	MealIndex   int
+	FoodIndex   int
+	ToMealIndex int
+}
+
+// Optional measurements are 0 when not taken
+type LogWeightData struct {
+	UserID  int64
+	Date    string
+	Weight  float64
+	Waist   float64
+	Hip     float64
+	Neck    float64
+	BodyFat float64
+}
+
+type WeightHistoryData struct {
+	UserID int64
+	Days   int // 0 for the whole history
+}
+
+// Nutrients of the report requests select the micronutrients to report, by
+// FDC number or name. Empty means models.DefaultNutrients, and "all" every
+// nutrient found in the logged foods.
+type GetReportData struct {
+	UserID    int64
+	Date      string
+	Nutrients []string
+}
+
+type PeriodReportData struct {
+	UserID    int64
+	From      string
+	To        string
+	Nutrients []string
+}
+
+// LookupBarcodeData finds a branded product by its GTIN-8, UPC-A, EAN-13 or
+// GTIN-14 barcode
+type LookupBarcodeData struct {
+	UserID int64
+	Code   string
+}
+
+// FoodLineData resolves a quick-add line, such as "150g chicken breast to
+// lunch" or "2 eggs, 1 slice toast breakfast", against the meals of a day and
+// the food search
+type FoodLineData struct {
+	UserID int64
+	Date   string
+	Text   string
+}
+
+// AddFoodLineData adds the foods picked for a quick-add line to a meal, all
+// or none of them: the meal at MealIndex or, when it is -1, a new meal named
+// Meal
+type AddFoodLineData struct {
+	UserID    int64
+	Date      string
+	MealIndex int
+	Meal      string
+	Foods     []FoodAmount
+}
+
+// FoodAmount is a quantity of a food, in grams
+type FoodAmount struct {
+	FoodID   string
+	Quantity float64
+}
+
+// FoodUsageData lists the foods a user logs, at most Limit of them, 10 when
+// it is 0
+type FoodUsageData struct {
+	UserID int64
+	Limit  int
+}
+
+// StarFoodData marks a food as a favorite of a user, or unmarks it
+type StarFoodData struct {
+	UserID  int64
+	FoodID  string
+	Starred bool
+}
+
+// CustomFoodData creates a custom food or, when ID is set, replaces one.
+// Custom foods are shared by every user, as FDC foods are. Nutrients are
+// amounts per 100g, or per serving when PerServing is set, keyed by FDC
+// nutrient number or name such as "kcal", "protein" or "sodium". Portions are
+// household measures such as "1 slice".
+type CustomFoodData struct {
+	ID          string
+	Name        string
+	BrandOwner  string
+	ServingSize float64
+	ServingText string
+	PerServing  bool
+	Nutrients   map[string]float64
+	Portions    []PortionInfo
+}
+
+type ListCustomFoodsData struct{}
+
+// RecipeData creates a recipe or, when ID is set, replaces one. Recipes are
+// shared by every user, as custom foods are. YieldWeight is the cooked weight
+// in grams, 0 when it is the weight of the ingredients.
+type RecipeData struct {
+	ID          string
+	Name        string
+	Servings    float64
+	YieldWeight float64
+	Ingredients []IngredientData
+}
+
+// IngredientData is a quantity of a food, in grams or, when Amount is set,
+// as a household quantity such as "2 cups"
+type IngredientData struct {
+	FoodID   string
+	Quantity float64
+	Amount   string
+}
+
+type ListRecipesData struct{}
+
 // Response Types
 type ProfileResponseData struct {
-	FirstName   string
-	LastName    string
-	Age         int
-	Weight      float64
-	Height      float64
-	Gender      string
-	Goal        string
-	BMI         float64
-	BodyFatPerc float64
+	ID                int64
+	FirstName         string
+	LastName          string
+	Age               int
+	Weight            float64
+	Height            float64
+	Gender            string
+	Goal              string
+	GoalRate          float64
+	GoalProtein       float64
+	ActivityLevel     string
+	BMI               float64
+	BodyFatPerc       float64
+	BMR               float64
+	BMRHarrisBenedict float64
+	BMRKatchMcArdle   float64
+	TDEE              float64
+	Targets           NutritionValues
 }
 
+type WeightHistoryResponse struct {
+	Entries      []WeighInInfo
+	WeeklyChange float64 // kg/week
+}
+
+type WeighInInfo struct {
+	Date    string
+	Weight  float64
+	Waist   float64
+	Hip     float64
+	Neck    float64
+	BodyFat float64
+	Trend   float64 // moving average of the weight
+}
+
+type UserListResponse struct {
+	Users []UserInfo
+}
+
+type UserInfo struct {
+	ID        int64
+	FirstName string
+	LastName  string
+}
+
+// SearchFoodResponseData is a page of search results. When filtered,
+// TotalHits counts the matching foods and Filtered the foods left out.
+// TotalPages only counts the pages within the first models.MaxResults foods,
+// past which searches cannot go.
 type SearchFoodResponseData struct {
-	Foods []FoodItem
+	Foods      []FoodItem
+	Page       int
+	PageSize   int
+	TotalHits  int
+	TotalPages int
+	Filtered   int
+}
+
+type LookupBarcodeResponse struct {
+	Food FoodItem
+}
+
+// FoodLineResponse is a resolved quick-add line. MealIndex is -1 when the
+// line names no meal, in which case Meal is empty, or a meal the day does not
+// have yet.
+type FoodLineResponse struct {
+	Meal      string
+	MealIndex int
+	Entries   []FoodEntryMatches
+}
+
+// FoodEntryMatches are the foods matching a food of a quick-add line, best
+// first, none when nothing matched
+type FoodEntryMatches struct {
+	Text    string
+	Matches []FoodMatch
+}
+
+// AddFoodLineResponse tells the meal the foods of a quick-add line were
+// added to
+type AddFoodLineResponse struct {
+	Meal      string
+	MealIndex int
+}
+
+// FoodMatch is a food matching a quick-add entry, with the entry quantity
+// in grams of it
+type FoodMatch struct {
+	Food     FoodItem
+	Quantity float64
+}
+
+// FoodUsageInfo is a food with how often the user logged it and the date
+// they last did, empty when they never did
+type FoodUsageInfo struct {
+	FoodItem
+	Uses     int
+	LastUsed string
+	Starred  bool
+}
+
+type FoodUsageResponse struct {
+	Foods []FoodUsageInfo
+}
+
+// CustomFoodInfo is a custom food with its other nutrients, per 100g
+type CustomFoodInfo struct {
+	FoodItem
+	Nutrients []NutrientValue
+}
+
+type CustomFoodResponse struct {
+	Food CustomFoodInfo
+}
+
+type CustomFoodListResponse struct {
+	Foods []CustomFoodInfo
+}
+
+// RecipeInfo describes a recipe with its nutritional values for the whole
+// dish, per serving and per 100g of the cooked dish. Its ID is the ID of the
+// food to add to meals.
+type RecipeInfo struct {
+	ID           string
+	Name         string
+	Servings     float64
+	YieldWeight  float64
+	RawWeight    float64
+	CookedWeight float64
+	Ingredients  []IngredientInfo
+	Total        NutritionValues
+	PerServing   NutritionValues
+	Per100g      NutritionValues
+}
+
+type IngredientInfo struct {
+	FoodID   string
+	Name     string
+	Quantity float64
+	Calories float64
+}
+
+type RecipeResponse struct {
+	Recipe RecipeInfo
+}
+
+type RecipeListResponse struct {
+	Recipes []RecipeInfo
+}
+
+// FoodQuantityResponse is the quantity of a food added or updated, in grams
+type FoodQuantityResponse struct {
+	Quantity float64
+}
+
+// PortionInfo is a household measure of a food, such as "1 cup, chopped"
+type PortionInfo struct {
+	Name  string
+	Grams float64
 }
 
 type FoodItem struct {
@@ -78,9 +523,17 @@ type FoodItem struct {
 	Carbs    float64
 	Fats     float64
 	Fiber    float64
+
+	DataType    string
+	BrandOwner  string
+	ServingSize float64
+	ServingText string
+	GTIN        string
+	Portions    []PortionInfo
 }
 
 type MealListResponse struct {
+	Date  string
 	Meals []MealInfo
 	Error string
 }
@@ -92,6 +545,30 @@ type MealInfo struct {
 	FoodItems []FoodItemInfo
 }
 
+// CopyMealsResponse counts the meals copied and their food items
+type CopyMealsResponse struct {
+	Meals int
+	Foods int
+}
+
+// MealTemplateInfo is a meal template, with the name of the meals it adds
+// and their calories
+type MealTemplateInfo struct {
+	ID        int64
+	Name      string
+	MealName  string
+	FoodItems []FoodItemInfo
+	Calories  float64
+}
+
+type MealTemplateResponse struct {
+	Template MealTemplateInfo
+}
+
+type MealTemplateListResponse struct {
+	Templates []MealTemplateInfo
+}
+
 type FoodItemInfo struct {
 	Name     string
 	Quantity float64
@@ -100,13 +577,70 @@ type FoodItemInfo struct {
 	Carbs    float64
 	Fats     float64
 	Fiber    float64
+	Portions []PortionInfo
 }
 
 type ReportResponse struct {
+	Date     string
 	Calories float64
 	Proteins float64
 	Carbs    float64
 	Fats     float64
 	Fiber    float64
 	Error    string
+
+	// Targets are the daily targets derived from the user's goal, and
+	// Remaining what is left of them, negative when over budget. Both are
+	// 0 when the profile measurements are unknown.
+	Targets   NutritionValues
+	Remaining NutritionValues
+
+	// Weight progress over the last weeks, 0 without weigh-ins. GoalRate is
+	// the targeted change, negative when losing weight.
+	WeightTrend  float64
+	WeeklyChange float64
+	GoalRate     float64
+
+	Nutrients []NutrientValue
+}
+
+// PeriodReportResponse aggregates the daily logs between two dates. Days
+// after today are left out, and averages only count the days with food logged.
+type PeriodReportResponse struct {
+	From        string
+	To          string
+	Days        int
+	DaysLogged  int
+	MissedDays  []string
+	Totals      NutritionValues
+	Averages    NutritionValues
+	Targets     NutritionValues
+	MinDay      DayTotals // logged day with the fewest calories
+	MaxDay      DayTotals // logged day with the most calories
+	DailyTotals []DayTotals
+	Nutrients   []NutrientValue
+}
+
+type DayTotals struct {
+	Date   string
+	Logged bool
+	NutritionValues
+}
+
+// NutrientValue is the amount of a micronutrient eaten. Average is the daily
+// average in period reports.
+type NutrientValue struct {
+	Number  string
+	Name    string
+	Unit    string
+	Amount  float64
+	Average float64
+}
+
+type NutritionValues struct {
+	Calories float64
+	Proteins float64
+	Carbs    float64
+	Fats     float64
+	Fiber    float64
 }
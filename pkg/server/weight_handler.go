@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"nutritionapp/pkg/models"
+	"time"
+)
+
+func (s *Server) handleLogWeight(untypedData any) Response {
+	data, ok := untypedData.(LogWeightData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if _, err := s.getUser(data.UserID); err != nil {
+		return Response{Error: err}
+	}
+
+	date, err := parseDate(data.Date)
+	if err != nil {
+		return Response{Error: err}
+	}
+	// The latest weigh-in is the current weight, which a mistyped date in
+	// the future would replace for good
+	if date.Format(DateFormat) > time.Now().Format(DateFormat) {
+		return Response{Error: invalidf("cannot log a weight in the future")}
+	}
+
+	if data.Weight <= 0 {
+		return Response{Error: invalidf("weight must be positive")}
+	}
+	if data.Waist < 0 || data.Hip < 0 || data.Neck < 0 {
+		return Response{Error: invalidf("measurements cannot be negative")}
+	}
+	if data.BodyFat < 0 || data.BodyFat >= 100 {
+		return Response{Error: invalidf("body fat must be a percentage")}
+	}
+
+	weighIn := &models.WeighIn{
+		Date:    date,
+		Weight:  data.Weight,
+		Waist:   data.Waist,
+		Hip:     data.Hip,
+		Neck:    data.Neck,
+		BodyFat: data.BodyFat,
+	}
+	if err := s.userDB.SaveWeighIn(data.UserID, weighIn); err != nil {
+		return Response{Error: fmt.Errorf("failed to save weight: %v", err)}
+	}
+
+	return Response{}
+}
+
+func (s *Server) handleWeightHistory(untypedData any) Response {
+	data, ok := untypedData.(WeightHistoryData)
+	if !ok {
+		return Response{Error: invalidf("invalid request data")}
+	}
+
+	if _, err := s.getUser(data.UserID); err != nil {
+		return Response{Error: err}
+	}
+
+	if data.Days < 0 {
+		return Response{Error: invalidf("number of days cannot be negative")}
+	}
+
+	var since time.Time
+	if data.Days > 0 {
+		since = time.Now().AddDate(0, 0, -data.Days+1)
+	}
+
+	weighIns, err := s.userDB.ListWeighIns(data.UserID, since)
+	if err != nil {
+		return Response{Error: fmt.Errorf("failed to load weight history: %v", err)}
+	}
+
+	trend := models.WeightTrend(weighIns)
+	var entries []WeighInInfo
+	for i, w := range weighIns {
+		entries = append(entries, WeighInInfo{
+			Date:    w.Date.Format(DateFormat),
+			Weight:  w.Weight,
+			Waist:   w.Waist,
+			Hip:     w.Hip,
+			Neck:    w.Neck,
+			BodyFat: w.BodyFat,
+			Trend:   trend[i],
+		})
+	}
+
+	return Response{
+		Data: WeightHistoryResponse{
+			Entries:      entries,
+			WeeklyChange: models.WeeklyChange(weighIns),
+		},
+	}
+}
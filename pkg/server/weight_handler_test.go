@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLogWeight(t *testing.T) {
+	today := time.Now()
+	tests := []struct {
+		name       string
+		date       string
+		data       LogWeightData
+		wantStatus int
+		wantWeight float64
+	}{
+		{"today", "", LogWeightData{Weight: 59}, http.StatusNoContent, 59},
+		{"yesterday", today.AddDate(0, 0, -1).Format(DateFormat), LogWeightData{Weight: 59}, http.StatusNoContent, 60},
+		{"tomorrow", today.AddDate(0, 0, 1).Format(DateFormat), LogWeightData{Weight: 95}, http.StatusBadRequest, 60},
+		{"next year", today.AddDate(1, 0, 0).Format(DateFormat), LogWeightData{Weight: 95}, http.StatusBadRequest, 60},
+		{"zero weight", "", LogWeightData{}, http.StatusBadRequest, 60},
+		{"negative waist", "", LogWeightData{Weight: 59, Waist: -1}, http.StatusBadRequest, 60},
+		{"body fat over 100%", "", LogWeightData{Weight: 59, BodyFat: 100}, http.StatusBadRequest, 60},
+		{"invalid date", "03/04/2024", LogWeightData{Weight: 59}, http.StatusBadRequest, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, userDB := newTestServer(t)
+			userID := createTestUser(t, s)
+
+			path := "/users/" + strconv.FormatInt(userID, 10) + "/weights"
+			if tt.date != "" {
+				path += "?date=" + tt.date
+			}
+			if status := doJSON(t, s, http.MethodPost, path, tt.data, nil); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if latest := userDB.GetLatestWeighIn(userID); latest.Weight != tt.wantWeight {
+				t.Errorf("latest weight = %v, want %v", latest.Weight, tt.wantWeight)
+			}
+		})
+	}
+}